@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bubbletea-scan/scanner"
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// Exit codes returned by headless mode
+const (
+	exitFound       = 0   // scan completed with at least one result
+	exitError       = 1   // invalid configuration or fatal error
+	exitNoResults   = 2   // scan completed without results
+	exitInterrupted = 130 // scan stopped by SIGINT/SIGTERM
+)
+
+// runHeadless runs the scan without the Bubble Tea program. The scan starts
+// immediately, every matching result is printed as one line on stdout and
+// diagnostics go to stderr, so the output can be piped to other tools.
+// A non-nil state resumes a checkpointed scan; results found before the
+// checkpoint are not printed again. It returns the process exit code.
+func runHeadless(cfg *scanner.Config, state *scanner.State) int {
+	// Stop the workers on Ctrl+C / SIGTERM; the output file is still written.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	engine := scanner.New(cfg)
+	if state != nil {
+		if err := engine.Restore(state); err != nil {
+			fmt.Fprintln(os.Stderr, ErrorStyle.Render(fmt.Sprintf("Error: %v", err)))
+			return exitError
+		}
+	}
+	// Results restored from the state file were printed by the previous run
+	seen := make(map[string]bool)
+	for _, result := range engine.Found() {
+		seen[result.Key()] = true
+	}
+	printed := make(chan struct{})
+	go func() {
+		defer close(printed)
+		for result := range engine.Results() {
+			seen[result.Key()] = true
+			fmt.Fprintln(os.Stdout, result.String())
+		}
+	}()
+
+	start := time.Now()
+	err := engine.Run(ctx)
+	<-printed
+	// Results found while stopping are recorded but no longer streamed
+	for _, result := range engine.Found() {
+		if !seen[result.Key()] {
+			fmt.Fprintln(os.Stdout, result.String())
+		}
+	}
+	// A fatal error (circuit breaker abort, unreadable wordlist) still writes
+	// the output file and the summary with what was collected so far
+	failed := err != nil && ctx.Err() == nil
+	if failed {
+		fmt.Fprintln(os.Stderr, ErrorStyle.Render(fmt.Sprintf("Error: %v", err)))
+	}
+
+	var tech map[string]string
+	if cfg.TechDetect && ctx.Err() == nil {
+		tech = detectarTecnologias(cfg)
+	}
+	results := engine.Found()
+	if cfg.OutputFile != "" {
+		if err := writeOutput(cfg, start, time.Now(), results, engine.Failures(), engine.Stats().Errors, tech); err != nil {
+			fmt.Fprintln(os.Stderr, ErrorStyle.Render(fmt.Sprintf("Error: %v", err)))
+			failed = true
+		}
+	}
+
+	if !cfg.Silent {
+		if cfg.Verbose {
+			for _, failure := range engine.Failures() {
+				fmt.Fprintln(os.Stderr, failure.String())
+			}
+		}
+		stats := engine.Stats()
+		errs := ""
+		if stats.FailedCount > 0 {
+			errs = fmt.Sprintf(" (%s)", stats.Errors)
+		}
+		fmt.Fprintf(os.Stderr, "[*] Processed: %d | Found: %d | Errors: %d%s | Elapsed: %s\n",
+			stats.ProcessedCount, len(results), stats.FailedCount, errs, time.Since(start).Round(time.Millisecond))
+		if stats.ReplayFailed > 0 {
+			fmt.Fprintf(os.Stderr, "[!] Replay failed for %d results: %s\n", stats.ReplayFailed, stats.ReplayError)
+		}
+	}
+
+	if failed {
+		return exitError
+	}
+	if ctx.Err() != nil {
+		return exitInterrupted
+	}
+	if len(results) == 0 {
+		return exitNoResults
+	}
+	return exitFound
+}
@@ -1,35 +1,299 @@
-// Package fasthttpproxy - local copy do original
+// Package internal holds helpers that are not part of the public API: the
+// proxy layer used by the HTTP clients and the technology detector.
 package internal
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
 	"net"
+	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/proxy"
 )
 
-// FasthttpHTTPDialer returns a dialer func for fasthttp that supports HTTP proxies
-func FasthttpHTTPDialer(proxyAddr string) func(addr string) (net.Conn, error) {
-	proxyURL, err := url.Parse(proxyAddr)
+// ProxyList is a set of proxies used in rotation, one per new connection
+type ProxyList struct {
+	urls []*url.URL
+	next atomic.Uint32
+}
+
+// ParseProxies parses a comma separated list of proxy URLs. Supported schemes
+// are http (the default when none is given), socks5 (host names resolved
+// locally) and socks5h (host names resolved by the proxy), with optional
+// user:pass@ credentials.
+func ParseProxies(list string) (*ProxyList, error) {
+	l := &ProxyList{}
+	for _, raw := range strings.Split(list, ",") {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+		if !strings.Contains(raw, "://") {
+			raw = "http://" + raw
+		}
+		u, err := url.Parse(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy %q: %w", raw, err)
+		}
+		u.Scheme = strings.ToLower(u.Scheme)
+		port := "80"
+		switch u.Scheme {
+		case "http":
+		case "socks5", "socks5h":
+			port = "1080"
+		default:
+			return nil, fmt.Errorf("unsupported proxy scheme %q in %s (use http, socks5 or socks5h)", u.Scheme, u.Redacted())
+		}
+		if u.Hostname() == "" {
+			return nil, fmt.Errorf("proxy %s has no host", u.Redacted())
+		}
+		if u.Port() == "" {
+			u.Host = net.JoinHostPort(u.Hostname(), port)
+		}
+		l.urls = append(l.urls, u)
+	}
+	if len(l.urls) == 0 {
+		return nil, fmt.Errorf("empty proxy list")
+	}
+	return l, nil
+}
+
+// Next returns the next proxy of the rotation
+func (l *ProxyList) Next() *url.URL {
+	i := l.next.Add(1) - 1
+	return l.urls[int(i%uint32(len(l.urls)))]
+}
+
+// ProxyDialer opens the connections of a fasthttp client through a
+// ProxyList. TLS targets are tunnelled with CONNECT (or SOCKS5); plain HTTP
+// targets are sent to HTTP proxies as absolute-URI requests.
+type ProxyDialer struct {
+	proxies *ProxyList
+	timeout time.Duration
+}
+
+// NewProxyDialer parses list (see ParseProxies). timeout bounds the
+// connection to the proxy and its CONNECT handshake.
+func NewProxyDialer(list string, timeout time.Duration) (*ProxyDialer, error) {
+	proxies, err := ParseProxies(list)
 	if err != nil {
-		return nil
+		return nil, err
 	}
-	return func(addr string) (net.Conn, error) {
-		conn, err := net.Dial("tcp", proxyURL.Host)
-		if err != nil {
-			return nil, err
+	return &ProxyDialer{proxies: proxies, timeout: timeout}, nil
+}
+
+// Tunnel returns a connection to addr through the next proxy: a CONNECT
+// tunnel for HTTP proxies, a SOCKS5 connection otherwise. It is meant for TLS
+// targets, whose handshake then runs end to end.
+func (d *ProxyDialer) Tunnel(addr string) (net.Conn, error) {
+	return d.tunnel(d.proxies.Next(), addr)
+}
+
+// Next returns the next proxy of the rotation, for TunnelVia
+func (d *ProxyDialer) Next() *url.URL {
+	return d.proxies.Next()
+}
+
+// TunnelVia is Tunnel through p, a proxy returned by Next
+func (d *ProxyDialer) TunnelVia(p *url.URL, addr string) (net.Conn, error) {
+	return d.tunnel(p, addr)
+}
+
+// Forward returns a connection for plain HTTP requests to addr. HTTP
+// proxies receive the requests themselves, rewritten to absolute-URI form;
+// SOCKS5 proxies tunnel the connection as with Tunnel.
+func (d *ProxyDialer) Forward(addr string) (net.Conn, error) {
+	p := d.proxies.Next()
+	if p.Scheme != "http" {
+		return d.tunnel(p, addr)
+	}
+	conn, err := net.DialTimeout("tcp", p.Host, d.timeout)
+	if err != nil {
+		return nil, fmt.Errorf("proxy %s: %w", p.Redacted(), err)
+	}
+	return &forwardConn{Conn: conn, origin: "http://" + addr, auth: authorization(p)}, nil
+}
+
+func (d *ProxyDialer) tunnel(p *url.URL, addr string) (net.Conn, error) {
+	if p.Scheme != "http" {
+		var auth *proxy.Auth
+		if p.User != nil {
+			password, _ := p.User.Password()
+			auth = &proxy.Auth{User: p.User.Username(), Password: password}
 		}
-		// HTTP CONNECT
-		_, err = conn.Write([]byte("CONNECT " + addr + " HTTP/1.1\r\nHost: " + addr + "\r\n\r\n"))
+		dialer, err := proxy.SOCKS5("tcp", p.Host, auth, &net.Dialer{Timeout: d.timeout})
 		if err != nil {
-			conn.Close()
 			return nil, err
 		}
-		// Read response (simplificado)
-		buf := make([]byte, 4096)
-		_, err = conn.Read(buf)
+		if p.Scheme == "socks5" {
+			if addr, err = d.resolve(addr); err != nil {
+				return nil, err
+			}
+		}
+		conn, err := dialer.Dial("tcp", addr)
 		if err != nil {
-			conn.Close()
-			return nil, err
+			return nil, fmt.Errorf("proxy %s: %w", p.Redacted(), err)
 		}
 		return conn, nil
 	}
+
+	conn, err := net.DialTimeout("tcp", p.Host, d.timeout)
+	if err != nil {
+		return nil, fmt.Errorf("proxy %s: %w", p.Redacted(), err)
+	}
+	if d.timeout > 0 {
+		conn.SetDeadline(time.Now().Add(d.timeout))
+	}
+	req := "CONNECT " + addr + " HTTP/1.1\r\nHost: " + addr + "\r\n"
+	if auth := authorization(p); auth != "" {
+		req += "Proxy-Authorization: " + auth + "\r\n"
+	}
+	if _, err := conn.Write([]byte(req + "\r\n")); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("proxy %s: %w", p.Redacted(), err)
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, &http.Request{Method: http.MethodConnect})
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("proxy %s: reading CONNECT reply: %w", p.Redacted(), err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("proxy %s refused CONNECT to %s: %s", p.Redacted(), addr, resp.Status)
+	}
+	conn.SetDeadline(time.Time{})
+	if br.Buffered() > 0 {
+		// The target spoke first; keep what was already read
+		return &bufferedConn{Conn: conn, r: br}, nil
+	}
+	return conn, nil
+}
+
+// resolve replaces the host name of addr with one of its IP addresses, so
+// that socks5 proxies are given an address rather than a name
+func (d *ProxyDialer) resolve(addr string) (string, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || net.ParseIP(host) != nil {
+		return addr, err
+	}
+	ctx := context.Background()
+	if d.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.timeout)
+		defer cancel()
+	}
+	ips, err := net.DefaultResolver.LookupIP(ctx, "ip", host)
+	if err != nil {
+		return "", err
+	}
+	// Prefer IPv4, which every SOCKS5 proxy can reach
+	ip := ips[0]
+	for _, candidate := range ips {
+		if candidate.To4() != nil {
+			ip = candidate
+			break
+		}
+	}
+	return net.JoinHostPort(ip.String(), port), nil
+}
+
+// authorization returns the Proxy-Authorization value for the credentials
+// of p, or "" when it has none
+func authorization(p *url.URL) string {
+	if p.User == nil {
+		return ""
+	}
+	password, _ := p.User.Password()
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(p.User.Username()+":"+password))
+}
+
+// bufferedConn reads the bytes buffered during the CONNECT handshake first
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(p []byte) (int, error) {
+	return c.r.Read(p)
+}
+
+// forwardConn is a connection to an HTTP proxy that rewrites every request
+// written to it from origin form (GET /path) to absolute form (GET
+// http://host:port/path) and adds the proxy credentials. Bodies are passed
+// through according to Content-Length; chunked request bodies are not
+// supported.
+type forwardConn struct {
+	net.Conn
+	origin string
+	auth   string
+
+	head []byte // request head being buffered
+	body int    // body bytes of the current request still to pass through
+}
+
+func (c *forwardConn) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		if c.body > 0 {
+			k := min(c.body, len(p))
+			if _, err := c.Conn.Write(p[:k]); err != nil {
+				return 0, err
+			}
+			c.body -= k
+			p = p[k:]
+			continue
+		}
+
+		c.head = append(c.head, p...)
+		p = nil
+		end := bytes.Index(c.head, []byte("\r\n\r\n"))
+		if end < 0 {
+			break
+		}
+		head, rest := c.head[:end+4], c.head[end+4:]
+		out, bodyLen := c.rewrite(head)
+		if _, err := c.Conn.Write(out); err != nil {
+			return 0, err
+		}
+		c.head, c.body, p = nil, bodyLen, rest
+	}
+	return n, nil
+}
+
+// rewrite turns a request head into absolute form and returns it with the
+// length of the body that follows
+func (c *forwardConn) rewrite(head []byte) ([]byte, int) {
+	lineEnd := bytes.Index(head, []byte("\r\n"))
+	line, headers := string(head[:lineEnd]), head[lineEnd+2:]
+	if parts := strings.SplitN(line, " ", 3); len(parts) == 3 && strings.HasPrefix(parts[1], "/") {
+		line = parts[0] + " " + c.origin + parts[1] + " " + parts[2]
+	}
+
+	bodyLen := 0
+	for _, h := range strings.Split(string(headers), "\r\n") {
+		name, value, ok := strings.Cut(h, ":")
+		if ok && strings.EqualFold(strings.TrimSpace(name), "Content-Length") {
+			bodyLen, _ = strconv.Atoi(strings.TrimSpace(value))
+		}
+	}
+
+	out := make([]byte, 0, len(head)+len(c.origin)+64)
+	out = append(out, line...)
+	out = append(out, "\r\n"...)
+	if c.auth != "" {
+		out = append(out, "Proxy-Authorization: "+c.auth+"\r\n"...)
+	}
+	out = append(out, headers...)
+	return out, bodyLen
 }
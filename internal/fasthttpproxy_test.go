@@ -0,0 +1,223 @@
+package internal
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestParseProxies(t *testing.T) {
+	l, err := ParseProxies("127.0.0.1:8080, socks5://u:p@10.0.0.1 ,http://proxy.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for i := 0; i < 4; i++ {
+		got = append(got, l.Next().String())
+	}
+	want := []string{"http://127.0.0.1:8080", "socks5://u:p@10.0.0.1:1080", "http://proxy.test:80", "http://127.0.0.1:8080"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("rotation = %v, want %v", got, want)
+	}
+
+	for _, bad := range []string{"", "ftp://x:21", "http://", "http://[::1"} {
+		if _, err := ParseProxies(bad); err == nil {
+			t.Errorf("ParseProxies(%q): expected an error", bad)
+		}
+	}
+}
+
+// connectProxy tunnels CONNECT requests carrying the expected credentials and
+// answers plain requests itself, echoing the request line
+func connectProxy(t *testing.T, wantAuth string) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Proxy-Authorization") != wantAuth {
+			w.WriteHeader(http.StatusProxyAuthRequired)
+			return
+		}
+		if r.Method != http.MethodConnect {
+			io.WriteString(w, "proxied "+r.Method+" "+r.RequestURI)
+			return
+		}
+		target, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		conn, _, _ := w.(http.Hijacker).Hijack()
+		io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n")
+		go io.Copy(target, conn)
+		go func() {
+			io.Copy(conn, target)
+			conn.Close()
+		}()
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestProxyDialer_HTTP(t *testing.T) {
+	target := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "direct "+r.URL.Path)
+	}))
+	defer target.Close()
+	proxy := connectProxy(t, "Basic dXNlcjpwYXNz") // user:pass
+
+	d, err := NewProxyDialer(strings.Replace(proxy.URL, "http://", "http://user:pass@", 1), 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &fasthttp.Client{
+		TLSConfig: target.Client().Transport.(*http.Transport).TLSClientConfig,
+		ConfigureClient: func(hc *fasthttp.HostClient) error {
+			if hc.IsTLS {
+				hc.Dial = d.Tunnel
+			} else {
+				hc.Dial = d.Forward
+			}
+			return nil
+		},
+	}
+
+	// TLS target: CONNECT tunnel, then the handshake with the target
+	status, body, err := client.Get(nil, target.URL+"/secure")
+	if err != nil || status != 200 || string(body) != "direct /secure" {
+		t.Errorf("tunnel: %d %q %v", status, body, err)
+	}
+
+	// Plain target: absolute-URI requests to the proxy, with a body in
+	// between to check the framing of consecutive requests
+	for _, path := range []string{"/a", "/b"} {
+		req := fasthttp.AcquireRequest()
+		resp := fasthttp.AcquireResponse()
+		req.SetRequestURI("http://plain.test:8000" + path)
+		req.Header.SetMethod(http.MethodPost)
+		req.SetBodyString("some body")
+		err := client.Do(req, resp)
+		if want := "proxied POST http://plain.test:8000" + path; err != nil || string(resp.Body()) != want {
+			t.Errorf("forward: %q %v, want %q", resp.Body(), err, want)
+		}
+		fasthttp.ReleaseRequest(req)
+		fasthttp.ReleaseResponse(resp)
+	}
+
+	// Wrong credentials: the CONNECT reply status is checked
+	d, _ = NewProxyDialer(proxy.URL, 5*time.Second)
+	if _, err := d.Tunnel(strings.TrimPrefix(target.URL, "https://")); err == nil || !strings.Contains(err.Error(), "407") {
+		t.Errorf("Tunnel = %v, want a 407 error", err)
+	}
+}
+
+// socks5Server accepts one username/password login and connects to the
+// requested address. Every requested host is sent on hosts when not nil.
+func socks5Server(t *testing.T, user, pass string, hosts chan<- string) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				buf := make([]byte, 512)
+				// Greeting: version, methods; choose username/password (2)
+				io.ReadFull(conn, buf[:2])
+				io.ReadFull(conn, buf[:buf[1]])
+				conn.Write([]byte{5, 2})
+				// Login: version, user, pass
+				io.ReadFull(conn, buf[:2])
+				u := make([]byte, buf[1])
+				io.ReadFull(conn, u)
+				io.ReadFull(conn, buf[:1])
+				p := make([]byte, buf[0])
+				io.ReadFull(conn, p)
+				if string(u) != user || string(p) != pass {
+					conn.Write([]byte{1, 1})
+					return
+				}
+				conn.Write([]byte{1, 0})
+				// Request: version, connect, reserved, address type
+				io.ReadFull(conn, buf[:4])
+				var host string
+				switch buf[3] {
+				case 1:
+					io.ReadFull(conn, buf[:4])
+					host = net.IP(buf[:4]).String()
+				case 3:
+					io.ReadFull(conn, buf[:1])
+					name := make([]byte, buf[0])
+					io.ReadFull(conn, name)
+					host = string(name)
+				}
+				if hosts != nil {
+					hosts <- host
+				}
+				io.ReadFull(conn, buf[:2])
+				addr := net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(buf[:2]))))
+				target, err := net.Dial("tcp", addr)
+				if err != nil {
+					conn.Write([]byte{5, 5, 0, 1, 0, 0, 0, 0, 0, 0})
+					return
+				}
+				defer target.Close()
+				conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+				go io.Copy(target, conn)
+				io.Copy(conn, target)
+			}(conn)
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func TestProxyDialer_SOCKS5(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "via socks "+r.RequestURI)
+	}))
+	defer target.Close()
+	hosts := make(chan string, 4)
+	addr := socks5Server(t, "user", "secret", hosts)
+
+	d, err := NewProxyDialer("socks5://user:secret@"+addr, 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &fasthttp.Client{Dial: d.Forward}
+	status, body, err := client.Get(nil, target.URL+"/x")
+	if err != nil || status != 200 || string(body) != "via socks /x" {
+		t.Errorf("socks5: %d %q %v", status, body, err)
+	}
+
+	<-hosts
+
+	// socks5 resolves host names locally, socks5h leaves them to the proxy
+	port := target.URL[strings.LastIndex(target.URL, ":")+1:]
+	for scheme, want := range map[string]string{"socks5": "127.0.0.1", "socks5h": "localhost"} {
+		d, _ := NewProxyDialer(scheme+"://user:secret@"+addr, 5*time.Second)
+		conn, err := d.Tunnel("localhost:" + port)
+		if err != nil {
+			t.Fatalf("%s: %v", scheme, err)
+		}
+		conn.Close()
+		if host := <-hosts; host != want {
+			t.Errorf("%s: proxy was asked for %q, want %q", scheme, host, want)
+		}
+	}
+
+	d, _ = NewProxyDialer("socks5://user:wrong@"+addr, 5*time.Second)
+	if _, err := d.Tunnel(strings.TrimPrefix(target.URL, "http://")); err == nil {
+		t.Error("expected an authentication error")
+	}
+}
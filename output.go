@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bubbletea-scan/scanner"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// writeOutput saves the results, the error counts, the requests that failed
+// after every retry (and detected tech) as JSON to cfg.OutputFile. It
+// returns the error when the file cannot be written; the caller decides how
+// to report it.
+func writeOutput(cfg *scanner.Config, start, end time.Time, results []scanner.Result, failures []scanner.Failure, errs scanner.ErrorCounts, tech map[string]string) error {
+	// Build metadata with timestamps and a safe subset of config values
+	cfgSummary := map[string]interface{}{
+		"url":              cfg.URL,
+		"wordlist":         cfg.Wordlist,
+		"threads":          cfg.Threads,
+		"method":           cfg.Method,
+		"status_codes":     cfg.StatusCodes,
+		"match_mode":       cfg.MatchMode,
+		"extensions":       cfg.Extensions,
+		"delay_ms":         cfg.Delay,
+		"retries":          cfg.Retries,
+		"retry_status":     cfg.RetryStatus,
+		"breaker":          cfg.BreakerThreshold,
+		"timeout_s":        cfg.Timeout,
+		"recursion":        cfg.Recursion,
+		"max_depth":        cfg.MaxDepth,
+		"auto_calibrate":   cfg.AutoCalibrate,
+		"follow_redirects": cfg.FollowRedirects,
+		"rate_limit":       cfg.RateLimit,
+		"transport":        cfg.Transport,
+		"auto_throttle":    cfg.AutoThrottle,
+		"subdomain":        cfg.Subdomain,
+		"subdomain_paths":  cfg.SubdomainPaths,
+		"try_both_schemes": cfg.TryBothSchemes,
+		"wildcard_detect":  cfg.WildcardDetect,
+		"wildcard_content": cfg.WildcardContent,
+		"vhost":            cfg.VHost,
+		"dns":              cfg.DNSOnly,
+		"tech_detect":      cfg.TechDetect,
+	}
+	if len(cfg.Targets) > 0 {
+		cfgSummary["targets"] = cfg.Targets
+	}
+	if cfg.RequestFile != "" {
+		cfgSummary["request_file"] = cfg.RequestFile
+	}
+	if cfg.VHostDomain != "" {
+		cfgSummary["vhost_domain"] = cfg.VHostDomain
+	}
+	if cfg.DNSOnly {
+		cfgSummary["record_types"] = cfg.DNSTypes
+		cfgSummary["resolvers"] = cfg.Resolvers
+	}
+	if len(cfg.Wordlists) > 0 {
+		cfgSummary["wordlists"] = cfg.Wordlists
+		cfgSummary["mode"] = cfg.Mode
+	}
+
+	out := struct {
+		Metadata struct {
+			Start           string                 `json:"start"`
+			End             string                 `json:"end"`
+			DurationSeconds float64                `json:"duration_seconds"`
+			Config          map[string]interface{} `json:"config"`
+		} `json:"metadata"`
+		Results  []scanner.Result    `json:"results"`
+		Errors   scanner.ErrorCounts `json:"errors"`
+		Failures []scanner.Failure   `json:"failures,omitempty"`
+		Detected map[string]string   `json:"detected_technologies,omitempty"`
+	}{}
+
+	out.Metadata.Start = start.UTC().Format(time.RFC3339)
+	out.Metadata.End = end.UTC().Format(time.RFC3339)
+	out.Metadata.DurationSeconds = end.Sub(start).Seconds()
+	out.Metadata.Config = cfgSummary
+	out.Results = results
+	if out.Results == nil {
+		out.Results = []scanner.Result{}
+	}
+	out.Errors = errs
+	out.Failures = failures
+	out.Detected = tech
+
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding results: %w", err)
+	}
+	if err := os.WriteFile(cfg.OutputFile, data, 0644); err != nil {
+		return fmt.Errorf("writing output file: %w", err)
+	}
+	if cfg.Verbose {
+		fmt.Fprintf(os.Stderr, "[VERBOSE] Results written to %s\n", cfg.OutputFile)
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package scanner
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Circuit breaker actions
+const (
+	BreakerPause = "pause"
+	BreakerAbort = "abort"
+)
+
+// Defaults used when Config.BreakerWindow and Config.BreakerCooldown are not set
+const (
+	defaultBreakerWindow   = 50
+	defaultBreakerCooldown = 30 * time.Second
+)
+
+// breaker is the error-rate circuit breaker. It keeps the outcome of the last
+// window requests of the whole scan and trips when the share of failures
+// reaches the threshold, which usually means a host went down or a WAF
+// started dropping connections.
+type breaker struct {
+	mu        sync.Mutex
+	threshold float64 // percent of failed requests
+	outcomes  []bool  // ring buffer, true for a failure
+	next      int
+	filled    int
+	failed    int
+
+	// trip is incremented on every trip and never goes back, so that a
+	// cooldown can tell whether it still belongs to the current one. open is
+	// set while a trip waits for its cooldown.
+	trip int
+	open bool
+}
+
+// newBreaker returns nil when Config.BreakerThreshold is not set
+func newBreaker(cfg *Config) *breaker {
+	if cfg.BreakerThreshold <= 0 {
+		return nil
+	}
+	window := cfg.BreakerWindow
+	if window <= 0 {
+		window = defaultBreakerWindow
+	}
+	return &breaker{threshold: cfg.BreakerThreshold, outcomes: make([]bool, window)}
+}
+
+// record adds the outcome of a request. It returns the failure rate in
+// percent and true when the breaker trips: the window is full and the rate
+// reached the threshold. A tripped breaker starts over with an empty window.
+func (b *breaker) record(failed bool) (float64, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.filled == len(b.outcomes) && b.outcomes[b.next] {
+		b.failed--
+	}
+	b.outcomes[b.next] = failed
+	if failed {
+		b.failed++
+	}
+	b.next = (b.next + 1) % len(b.outcomes)
+	b.filled = min(b.filled+1, len(b.outcomes))
+
+	rate := 100 * float64(b.failed) / float64(len(b.outcomes))
+	if b.filled < len(b.outcomes) || rate < b.threshold {
+		return rate, false
+	}
+	clear(b.outcomes)
+	b.next, b.filled, b.failed = 0, 0, 0
+	return rate, true
+}
+
+// reset forgets the current trip so that a pending automatic resume does
+// not release a pause requested by the user
+func (b *breaker) reset() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	b.open = false
+	b.mu.Unlock()
+}
+
+// ValidateBreaker checks the circuit breaker settings of cfg
+func ValidateBreaker(cfg *Config) error {
+	if cfg.BreakerThreshold < 0 || cfg.BreakerThreshold > 100 {
+		return fmt.Errorf("circuit breaker threshold must be a percentage between 0 and 100")
+	}
+	switch strings.ToLower(cfg.BreakerAction) {
+	case "", BreakerPause, BreakerAbort:
+		return nil
+	}
+	return fmt.Errorf("unknown circuit breaker action %q (use %s or %s)", cfg.BreakerAction, BreakerPause, BreakerAbort)
+}
+
+// recordOutcome feeds the outcome of a request to the circuit breaker and
+// pauses or aborts the scan when it trips. A paused scan resumes by itself
+// after Config.BreakerCooldown seconds, or earlier with Resume.
+func (s *Scanner) recordOutcome(failed bool) {
+	b := s.breaker
+	if b == nil {
+		return
+	}
+	rate, tripped := b.record(failed)
+	if !tripped {
+		return
+	}
+
+	msg := fmt.Sprintf("circuit breaker: %.0f%% of the last %d requests failed", rate, len(b.outcomes))
+	if strings.ToLower(s.config.BreakerAction) == BreakerAbort {
+		s.fail(errors.New(msg))
+		s.cancel()
+		return
+	}
+
+	cooldown := time.Duration(s.config.BreakerCooldown) * time.Second
+	if cooldown <= 0 {
+		cooldown = defaultBreakerCooldown
+	}
+	b.mu.Lock()
+	b.trip++
+	b.open = true
+	trip := b.trip
+	b.mu.Unlock()
+
+	s.statsMu.Lock()
+	s.stats.Breaker = fmt.Sprintf("%s, paused for %v", msg, cooldown)
+	s.statsMu.Unlock()
+	s.gate.pause()
+	s.publishProgress()
+
+	go func() {
+		timer := time.NewTimer(cooldown)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-s.ctx.Done():
+			return
+		}
+		b.mu.Lock()
+		current := b.open && b.trip == trip
+		b.mu.Unlock()
+		if current {
+			s.Resume()
+		}
+	}()
+}
@@ -0,0 +1,160 @@
+package scanner
+
+import (
+	"bytes"
+	"crypto/rand"
+	"strings"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// calibrationProbes is the number of random paths requested per directory
+const calibrationProbes = 3
+
+// profile is the shape of a response, used by the matchers and filters and
+// to recognise soft-404 pages
+type profile struct {
+	status   int
+	size     int
+	lines    int
+	words    int
+	duration time.Duration
+}
+
+// measure computes the profile of a response body received after duration
+func measure(status int, body []byte, duration time.Duration) profile {
+	p := profile{status: status, size: len(body), words: len(bytes.Fields(body)), duration: duration}
+	p.lines = bytes.Count(body, []byte("\n"))
+	if p.size > 0 {
+		p.lines++
+	}
+	return p
+}
+
+// baseline is the soft-404 profile of a directory: the status every random
+// probe returned and the metrics that were identical on every probe.
+type baseline struct {
+	status                         int
+	size, lines, words             int
+	sameSize, sameLines, sameWords bool
+}
+
+// learnBaseline builds a baseline from the probe responses. It returns nil
+// when the probes disagree on the status or on every metric, since nothing
+// reliable can be filtered then.
+func learnBaseline(probes []profile) *baseline {
+	if len(probes) == 0 {
+		return nil
+	}
+	first := probes[0]
+	b := &baseline{
+		status:   first.status,
+		size:     first.size,
+		lines:    first.lines,
+		words:    first.words,
+		sameSize: true, sameLines: true, sameWords: true,
+	}
+	for _, p := range probes[1:] {
+		if p.status != b.status {
+			return nil
+		}
+		b.sameSize = b.sameSize && p.size == b.size
+		b.sameLines = b.sameLines && p.lines == b.lines
+		b.sameWords = b.sameWords && p.words == b.words
+	}
+	if !b.sameSize && !b.sameLines && !b.sameWords {
+		return nil
+	}
+	return b
+}
+
+// matches reports whether a response looks like the directory's soft-404 page
+func (b *baseline) matches(p profile) bool {
+	if b == nil || p.status != b.status {
+		return false
+	}
+	return (!b.sameSize || p.size == b.size) &&
+		(!b.sameLines || p.lines == b.lines) &&
+		(!b.sameWords || p.words == b.words)
+}
+
+// randomWord returns a path segment of n characters (at most 26) that
+// should not exist on any server
+func randomWord(n int) string {
+	return strings.ToLower(rand.Text())[:n]
+}
+
+// calibrate requests random nonexistent entries in the base URL (target ==
+// nil) or in a discovered directory and stores the learned baseline, which
+// the workers use to drop soft-404 responses. It returns false if the scan
+// was stopped.
+func (h *host) calibrate(target *RecursionTarget) bool {
+	if !h.s.gate.wait(h.s.ctx) {
+		return false
+	}
+
+	req := fasthttp.AcquireRequest()
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseRequest(req)
+	defer fasthttp.ReleaseResponse(resp)
+
+	dir := ""
+	if target != nil {
+		dir = target.URL
+	}
+
+	// Probes of different lengths, so that a page reflecting the requested
+	// path does not look like a fixed size
+	var probes []profile
+	for i := 0; i < calibrationProbes; i++ {
+		n := 8 + 6*i
+		job := Job{URL: randomWord(n), dir: dir}
+		if h.s.config.VHost {
+			job = Job{Label: randomWord(n)}
+		} else if target != nil {
+			job.URL = target.URL + job.URL
+		} else if h.s.keywordMode() {
+			job = Job{Values: make([]string, len(h.s.config.Wordlists))}
+			for k := range job.Values {
+				job.Values[k] = randomWord(n)
+			}
+		}
+
+		if !h.pace() {
+			return false
+		}
+		url := h.buildURL(job)
+		h.prepareRequest(req, job, url)
+		err := h.s.transport.Do(req, resp, time.Time{})
+		h.observe(resp, err)
+		if err != nil {
+			continue
+		}
+		probes = append(probes, measure(resp.StatusCode(), resp.Body(), 0))
+	}
+
+	b := learnBaseline(probes)
+	h.mu.Lock()
+	h.baselines[dir] = b
+	h.mu.Unlock()
+
+	if b != nil {
+		h.s.statsMu.Lock()
+		h.s.stats.Calibrated++
+		h.s.statsMu.Unlock()
+	}
+	return true
+}
+
+// softNotFound reports whether a response matches the baseline of the
+// directory its job belongs to
+func (h *host) softNotFound(job Job, p profile) bool {
+	if !h.s.config.AutoCalibrate && !h.s.config.VHost {
+		return false
+	}
+	h.mu.Lock()
+	b := h.baselines[job.dir]
+	h.mu.Unlock()
+	return b.matches(p)
+}
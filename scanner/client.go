@@ -0,0 +1,50 @@
+package scanner
+
+import (
+	"bubbletea-scan/internal"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// NewFastHTTPClient builds the fasthttp client used by the workers. It fails
+// when the proxy list or the TLS settings are invalid.
+func NewFastHTTPClient(cfg *Config) (*fasthttp.Client, error) {
+	tlsConfig, err := newTLSConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	client := &fasthttp.Client{
+		ReadTimeout:                   time.Duration(cfg.Timeout) * time.Second,
+		WriteTimeout:                  time.Duration(cfg.Timeout) * time.Second,
+		MaxIdleConnDuration:           time.Second * 30,
+		MaxConnsPerHost:               cfg.Threads * 2,
+		MaxConnDuration:               time.Second * 60,
+		MaxResponseBodySize:           maxResponseBodySize,
+		ReadBufferSize:                4096,
+		WriteBufferSize:               4096,
+		MaxConnWaitTimeout:            time.Second * 5,
+		DisableHeaderNamesNormalizing: false,
+		DisablePathNormalizing:        false,
+		TLSConfig:                     tlsConfig,
+	}
+
+	// Configure the proxies if provided: TLS targets are tunnelled, plain
+	// HTTP targets are requested from HTTP proxies in absolute form
+	if cfg.Proxy != "" {
+		dialer, err := internal.NewProxyDialer(cfg.Proxy, time.Duration(cfg.Timeout)*time.Second)
+		if err != nil {
+			return nil, err
+		}
+		client.ConfigureClient = func(hc *fasthttp.HostClient) error {
+			if hc.IsTLS {
+				hc.Dial = dialer.Tunnel
+			} else {
+				hc.Dial = dialer.Forward
+			}
+			return nil
+		}
+	}
+
+	return client, nil
+}
@@ -0,0 +1,129 @@
+package scanner
+
+// Config holds scanner configuration populated from CLI flags
+type Config struct {
+	URL string
+	// Base URLs scanned side by side, each with its own Threads and RateLimit
+	// budget. When empty, URL is the only target.
+	Targets     []string
+	Wordlist    string
+	Threads     int
+	Method      string
+	StatusCodes string
+	Extensions  string
+	Headers     []string
+	Delay       int
+	Retries     int
+	Timeout     int
+	Recursion   bool
+	MaxDepth    int
+	FilterSize  string
+	FilterLines string
+	FilterRegex string
+	NoTLS       bool
+	UserAgent   string
+	Cookies     string
+	// Positive matchers on top of StatusCodes, combined according to
+	// MatchMode (MatchModeOr or MatchModeAnd)
+	MatchSize  string
+	MatchLines string
+	MatchWords string
+	MatchRegex string
+	MatchMode  string
+	// Filters on status code and word count
+	FilterStatus string
+	FilterWords  string
+	// Follow the redirects of 3xx results, up to MaxRedirects hops
+	FollowRedirects bool
+	MaxRedirects    int
+	// Match and filter on response time in milliseconds, e.g. ">2000ms"
+	MatchTime  string
+	FilterTime string
+	// Learn the soft-404 response of every scanned directory from random
+	// paths and drop responses that match it.
+	AutoCalibrate bool
+	// Adapt the request rate of every target to its responses: slow down on
+	// 429/503 and timeouts, honor Retry-After and ramp back up to RateLimit
+	// (or no limit) once the target recovers.
+	AutoThrottle bool
+	// Retry policy: exponential backoff from RetryBackoff up to MaxBackoff
+	// (milliseconds), statuses retried like transport errors, and a deadline
+	// in seconds for a request and all its retries (0 for none)
+	RetryBackoff    int
+	MaxBackoff      int
+	RetryStatus     string
+	RequestDeadline int
+	// Error-rate circuit breaker: when BreakerThreshold percent of the last
+	// BreakerWindow requests failed, BreakerAction (BreakerPause or
+	// BreakerAbort) is taken. A paused scan resumes after BreakerCooldown
+	// seconds. Disabled when BreakerThreshold is 0.
+	BreakerThreshold float64
+	BreakerWindow    int
+	BreakerAction    string
+	BreakerCooldown  int
+	// HTTP backend: TransportFastHTTP (default) or TransportNetHTTP, which
+	// can negotiate HTTP/2
+	Transport string
+	// Matching requests are sent again through this proxy (same syntax as
+	// Proxy), while the scan itself uses Proxy or a direct connection
+	ReplayProxy string
+	// TLS settings: client certificate and key (PEM; the key may be in the
+	// certificate file), a CA bundle trusted on top of the system roots, the
+	// server name sent in SNI and verified, and the TLS versions allowed
+	// ("1.0" to "1.3", empty for the Go defaults)
+	ClientCert    string
+	ClientKey     string
+	CACert        string
+	SNI           string
+	TLSMinVersion string
+	TLSMaxVersion string
+	// Request body; like the method, headers and cookies it may contain keywords
+	Data string
+	// Raw request file the template was loaded from (informational)
+	RequestFile string
+	Proxy       string // comma separated http:// or socks5:// URLs, rotated per connection
+	RateLimit   int
+	Silent      bool
+	Verbose     bool
+	OutputFile  string
+	TechDetect  bool
+	Subdomain   bool
+	// When true, combine subdomains and paths (cartesian product). Very costly.
+	SubdomainPaths bool
+	// Try each subdomain label over https first, then over http when https
+	// cannot be reached (transport error).
+	TryBothSchemes bool
+	// Detect wildcard DNS and skip wildcard results when present. Several
+	// random names are resolved in the immediate parent zone of every
+	// candidate, so a nested zone is compared with its own wildcard, through
+	// Resolvers when set.
+	WildcardDetect bool
+	// Also fingerprint the HTTP response of the wildcard: a candidate is
+	// skipped only when both its addresses and its response match.
+	WildcardContent bool
+	// Virtual host fuzzing: every request goes to URL with the Host header
+	// set to label.VHostDomain (the URL's host name by default), and
+	// responses matching the one of a random virtual host are dropped.
+	VHost       bool
+	VHostDomain string
+	// DNS-only subdomain brute force: every label is resolved as
+	// label.domain with DNSThreads workers (Threads when 0) against the
+	// comma separated Resolvers (ip[:port], the system ones when empty),
+	// asking for DNSTypes (A, AAAA, CNAME; A when empty). A failed query is
+	// sent to the next resolver, up to Retries times, and times out after
+	// DNSTimeout milliseconds. No HTTP request is made.
+	DNSOnly    bool
+	DNSThreads int
+	Resolvers  string
+	DNSTypes   string
+	DNSTimeout int
+	// Keyword-bound wordlists. When set they replace Wordlist and every
+	// keyword is substituted in the request; Mode (ModeClusterbomb or
+	// ModePitchfork) selects how the lists are combined.
+	Wordlists []KeywordWordlist
+	Mode      string
+	// Write a checkpoint to StateFile every CheckpointInterval seconds so the
+	// scan can be resumed after an interruption. Disabled when empty.
+	StateFile          string
+	CheckpointInterval int
+}
@@ -0,0 +1,38 @@
+package scanner
+
+import "time"
+
+// resolveJob looks up one label of a DNS-only scan and emits the name with
+// its records when it exists. It returns false if the scan was stopped.
+func (h *host) resolveJob(job Job) bool {
+	name := job.Label + "." + h.dnsDomain
+
+	h.s.statsMu.Lock()
+	h.s.stats.CurrentPath = name
+	h.s.statsMu.Unlock()
+
+	sent := time.Now()
+	records, attempts, err := h.s.resolver.resolve(h.s.ctx, name)
+	if h.s.ctx.Err() != nil {
+		return false
+	}
+	h.s.recordOutcome(err != nil)
+	if err != nil {
+		h.s.recordFailure(h, Failure{
+			Path:     name,
+			Attempts: attempts,
+			Category: classifyError(err),
+			Error:    err.Error(),
+		})
+		return true
+	}
+	if len(records) == 0 {
+		return true
+	}
+	// Names answered like a random name of the same zone are wildcards
+	if h.s.config.WildcardDetect && h.s.wildcardFor(parentZone(name)).matches(recordValues(records)) {
+		return true
+	}
+	h.s.emit(h, Result{Path: name, Records: records, Duration: time.Since(sent)})
+	return true
+}
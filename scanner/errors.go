@@ -0,0 +1,106 @@
+package scanner
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"syscall"
+
+	"github.com/valyala/fasthttp"
+)
+
+// Categories of failed requests
+const (
+	ErrorTimeout = "timeout"
+	ErrorRefused = "refused"
+	ErrorReset   = "reset"
+	ErrorTLS     = "tls"
+	ErrorDNS     = "dns"
+	ErrorStatus  = "status"
+	ErrorOther   = "other"
+)
+
+// ErrorCounts counts the requests that failed after every retry, by category
+type ErrorCounts struct {
+	Timeout int `json:"timeout"`
+	Refused int `json:"refused"`
+	Reset   int `json:"reset"`
+	TLS     int `json:"tls"`
+	DNS     int `json:"dns"`
+	Status  int `json:"status"`
+	Other   int `json:"other"`
+}
+
+// Total returns the number of failed requests
+func (c ErrorCounts) Total() int {
+	return c.Timeout + c.Refused + c.Reset + c.TLS + c.DNS + c.Status + c.Other
+}
+
+// String lists the non-zero categories, e.g. "timeout 3, reset 1"
+func (c ErrorCounts) String() string {
+	var parts []string
+	for _, n := range []struct {
+		name  string
+		count int
+	}{
+		{ErrorTimeout, c.Timeout}, {ErrorRefused, c.Refused}, {ErrorReset, c.Reset},
+		{ErrorTLS, c.TLS}, {ErrorDNS, c.DNS}, {ErrorStatus, c.Status}, {ErrorOther, c.Other},
+	} {
+		if n.count > 0 {
+			parts = append(parts, fmt.Sprintf("%s %d", n.name, n.count))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// add counts one failure of the given category
+func (c *ErrorCounts) add(category string) {
+	switch category {
+	case ErrorTimeout:
+		c.Timeout++
+	case ErrorRefused:
+		c.Refused++
+	case ErrorReset:
+		c.Reset++
+	case ErrorTLS:
+		c.TLS++
+	case ErrorDNS:
+		c.DNS++
+	case ErrorStatus:
+		c.Status++
+	default:
+		c.Other++
+	}
+}
+
+// classifyError returns the category of a transport error
+func classifyError(err error) string {
+	var dnsErr *net.DNSError
+	var certErr *tls.CertificateVerificationError
+	var recordErr tls.RecordHeaderError
+	var alertErr tls.AlertError
+	var unknownAuth x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+
+	switch {
+	case isTimeout(err):
+		return ErrorTimeout
+	case errors.As(err, &dnsErr):
+		return ErrorDNS
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return ErrorRefused
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.EPIPE),
+		errors.Is(err, fasthttp.ErrConnectionClosed), errors.Is(err, io.EOF),
+		errors.Is(err, io.ErrUnexpectedEOF):
+		return ErrorReset
+	case errors.As(err, &certErr), errors.As(err, &recordErr), errors.As(err, &alertErr),
+		errors.As(err, &unknownAuth), errors.As(err, &hostnameErr),
+		strings.Contains(err.Error(), "tls:"):
+		return ErrorTLS
+	}
+	return ErrorOther
+}
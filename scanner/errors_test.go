@@ -0,0 +1,62 @@
+package scanner
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{fasthttp.ErrTimeout, ErrorTimeout},
+		{fasthttp.ErrDialTimeout, ErrorTimeout},
+		{&net.OpError{Op: "read", Err: os.ErrDeadlineExceeded}, ErrorTimeout},
+		{&net.DNSError{Err: "no such host", Name: "nope.invalid", IsNotFound: true}, ErrorDNS},
+		{&net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}, ErrorRefused},
+		{&net.OpError{Op: "read", Err: os.NewSyscallError("read", syscall.ECONNRESET)}, ErrorReset},
+		{fasthttp.ErrConnectionClosed, ErrorReset},
+		{fmt.Errorf("reading response: %w", io.EOF), ErrorReset},
+		{tls.RecordHeaderError{Msg: "first record does not look like a TLS handshake"}, ErrorTLS},
+		{errors.New("remote error: tls: handshake failure"), ErrorTLS},
+		{errors.New("something else"), ErrorOther},
+	}
+	for _, tt := range tests {
+		if got := classifyError(tt.err); got != tt.want {
+			t.Errorf("classifyError(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestBreaker(t *testing.T) {
+	b := newBreaker(&Config{BreakerThreshold: 50, BreakerWindow: 4})
+	for i, failed := range []bool{true, true, false} {
+		if _, tripped := b.record(failed); tripped {
+			t.Fatalf("tripped after %d requests, before the window is full", i+1)
+		}
+	}
+	rate, tripped := b.record(false)
+	if !tripped || rate != 50 {
+		t.Fatalf("record = %v %v, want a trip at 50%%", rate, tripped)
+	}
+
+	// The window starts over after a trip and slides afterwards
+	for _, failed := range []bool{true, false, false, false, false} {
+		if _, tripped := b.record(failed); tripped {
+			t.Fatal("tripped below the threshold")
+		}
+	}
+
+	if newBreaker(&Config{}) != nil {
+		t.Error("breaker enabled without a threshold")
+	}
+}
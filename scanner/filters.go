@@ -0,0 +1,261 @@
+package scanner
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Ways of combining the response matchers
+const (
+	// MatchModeOr keeps a response when any matcher matches it
+	MatchModeOr = "or"
+	// MatchModeAnd keeps a response only when every matcher matches it
+	MatchModeAnd = "and"
+)
+
+// filters holds the parsed matchers and filters. The status codes are always
+// required; the other matchers are combined with AND or OR, and a response
+// that matches any filter is dropped.
+type filters struct {
+	statusCodes *numberSet
+
+	// Positive matchers (nil when not set)
+	matchSize  *numberSet
+	matchLines *numberSet
+	matchWords *numberSet
+	matchTime  *numberSet // milliseconds
+	matchRegex *regexp.Regexp
+	matchAll   bool
+
+	// Negative filters (nil when not set)
+	status *numberSet
+	size   *numberSet
+	lines  *numberSet
+	words  *numberSet
+	time   *numberSet // milliseconds
+	regex  *regexp.Regexp
+}
+
+// ValidateFilters checks the matcher and filter settings of cfg, so that
+// invalid input is reported before a scan starts
+func ValidateFilters(cfg *Config) error {
+	_, err := parseFilters(cfg)
+	return err
+}
+
+func parseFilters(cfg *Config) (*filters, error) {
+	f := &filters{}
+	sets := []struct {
+		dst     **numberSet
+		list    string
+		name    string
+		classes bool
+		parse   func(string) (int, error)
+	}{
+		{&f.statusCodes, cfg.StatusCodes, "status codes", true, parseNumber},
+		{&f.matchSize, cfg.MatchSize, "size matcher", false, parseNumber},
+		{&f.matchLines, cfg.MatchLines, "lines matcher", false, parseNumber},
+		{&f.matchWords, cfg.MatchWords, "words matcher", false, parseNumber},
+		{&f.matchTime, cfg.MatchTime, "time matcher", false, parseMillis},
+		{&f.status, cfg.FilterStatus, "status filter", true, parseNumber},
+		{&f.size, cfg.FilterSize, "size filter", false, parseNumber},
+		{&f.lines, cfg.FilterLines, "lines filter", false, parseNumber},
+		{&f.words, cfg.FilterWords, "words filter", false, parseNumber},
+		{&f.time, cfg.FilterTime, "time filter", false, parseMillis},
+	}
+	for _, set := range sets {
+		parsed, err := parseNumberSet(set.list, set.classes, set.parse)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q: %w", set.name, set.list, err)
+		}
+		*set.dst = parsed
+	}
+
+	switch strings.ToLower(cfg.MatchMode) {
+	case "", MatchModeOr:
+	case MatchModeAnd:
+		f.matchAll = true
+	default:
+		return nil, fmt.Errorf("unknown match mode %q (use %s or %s)", cfg.MatchMode, MatchModeOr, MatchModeAnd)
+	}
+
+	var err error
+	if cfg.MatchRegex != "" {
+		if f.matchRegex, err = regexp.Compile(cfg.MatchRegex); err != nil {
+			return nil, fmt.Errorf("invalid match regex: %w", err)
+		}
+	}
+	if cfg.FilterRegex != "" {
+		if f.regex, err = regexp.Compile(cfg.FilterRegex); err != nil {
+			return nil, fmt.Errorf("invalid filter regex: %w", err)
+		}
+	}
+	return f, nil
+}
+
+// numberSet is a parsed list of numbers: single values, ranges (100-250),
+// comparisons (>1000, <=10), "all" and, for status codes, classes (2xx).
+type numberSet struct {
+	all    bool
+	ranges []numberRange
+}
+
+// numberRange is an inclusive range of numbers
+type numberRange struct {
+	lo, hi int
+}
+
+// parseNumberSet parses a comma separated number list, reading every number
+// with parseNumber or parseMillis. It returns nil for an empty list. classes
+// allows status classes such as 2xx.
+func parseNumberSet(list string, classes bool, parse func(string) (int, error)) (*numberSet, error) {
+	if strings.TrimSpace(list) == "" {
+		return nil, nil
+	}
+	set := &numberSet{}
+	for _, item := range strings.Split(list, ",") {
+		item = strings.ToLower(strings.TrimSpace(item))
+		switch {
+		case item == "":
+			return nil, fmt.Errorf("empty entry")
+		case item == "all":
+			set.all = true
+		case classes && len(item) == 3 && strings.HasSuffix(item, "xx"):
+			class, err := strconv.Atoi(item[:1])
+			if err != nil || class < 1 || class > 5 {
+				return nil, fmt.Errorf("unknown status class %q (use 1xx to 5xx)", item)
+			}
+			set.ranges = append(set.ranges, numberRange{class * 100, class*100 + 99})
+		case strings.HasPrefix(item, ">=") || strings.HasPrefix(item, "<="):
+			n, err := parse(item[2:])
+			if err != nil {
+				return nil, err
+			}
+			if item[0] == '>' {
+				set.ranges = append(set.ranges, numberRange{n, math.MaxInt})
+			} else {
+				set.ranges = append(set.ranges, numberRange{math.MinInt, n})
+			}
+		case strings.HasPrefix(item, ">") || strings.HasPrefix(item, "<"):
+			n, err := parse(item[1:])
+			if err != nil {
+				return nil, err
+			}
+			if item[0] == '>' {
+				set.ranges = append(set.ranges, numberRange{n + 1, math.MaxInt})
+			} else {
+				set.ranges = append(set.ranges, numberRange{math.MinInt, n - 1})
+			}
+		case strings.Contains(item, "-"):
+			bounds := strings.SplitN(item, "-", 2)
+			lo, err := parse(bounds[0])
+			if err != nil {
+				return nil, err
+			}
+			hi, err := parse(bounds[1])
+			if err != nil {
+				return nil, err
+			}
+			if lo > hi {
+				return nil, fmt.Errorf("range %q is reversed", item)
+			}
+			set.ranges = append(set.ranges, numberRange{lo, hi})
+		default:
+			n, err := parse(item)
+			if err != nil {
+				return nil, err
+			}
+			set.ranges = append(set.ranges, numberRange{n, n})
+		}
+	}
+	return set, nil
+}
+
+// parseNumber parses a non-negative integer
+func parseNumber(s string) (int, error) {
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%q is not a valid number", s)
+	}
+	return n, nil
+}
+
+// parseMillis parses a duration in milliseconds: a plain number or a Go
+// duration such as 2000ms or 1.5s
+func parseMillis(s string) (int, error) {
+	s = strings.TrimSpace(s)
+	if n, err := strconv.Atoi(s); err == nil && n >= 0 {
+		return n, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("%q is not a valid duration (use e.g. 500ms or 2s)", s)
+	}
+	return int(d.Milliseconds()), nil
+}
+
+// has reports whether n is in the set. A nil set contains nothing.
+func (s *numberSet) has(n int) bool {
+	if s == nil {
+		return false
+	}
+	if s.all {
+		return true
+	}
+	for _, r := range s.ranges {
+		if n >= r.lo && n <= r.hi {
+			return true
+		}
+	}
+	return false
+}
+
+// filtered reports whether a response matches any of the filters
+func (f *filters) filtered(p profile, body []byte) bool {
+	return f.status.has(p.status) ||
+		f.size.has(p.size) ||
+		f.lines.has(p.lines) ||
+		f.words.has(p.words) ||
+		f.time.has(int(p.duration.Milliseconds())) ||
+		(f.regex != nil && f.regex.Match(body))
+}
+
+// matched reports whether a response has a matching status code and passes
+// the other matchers. Without matchers the status code alone decides.
+func (f *filters) matched(p profile, body []byte) bool {
+	if !f.statusCodes.has(p.status) {
+		return false
+	}
+
+	var results []bool
+	if f.matchSize != nil {
+		results = append(results, f.matchSize.has(p.size))
+	}
+	if f.matchLines != nil {
+		results = append(results, f.matchLines.has(p.lines))
+	}
+	if f.matchWords != nil {
+		results = append(results, f.matchWords.has(p.words))
+	}
+	if f.matchTime != nil {
+		results = append(results, f.matchTime.has(int(p.duration.Milliseconds())))
+	}
+	if f.matchRegex != nil {
+		results = append(results, f.matchRegex.Match(body))
+	}
+	if len(results) == 0 {
+		return true
+	}
+
+	for _, ok := range results {
+		if ok != f.matchAll {
+			// OR: the first match decides; AND: the first miss decides
+			return ok
+		}
+	}
+	return f.matchAll
+}
@@ -0,0 +1,84 @@
+package scanner
+
+import "testing"
+
+func TestFilters(t *testing.T) {
+	page := []byte("<title>Index of /backup</title>\n<a href=db.sql>db.sql</a>\n")
+	p := measure(200, page, 0)
+
+	cases := []struct {
+		name string
+		cfg  Config
+		want bool
+	}{
+		{"status only", Config{StatusCodes: "200"}, true},
+		{"status not matched", Config{StatusCodes: "301"}, false},
+		{"match regex", Config{StatusCodes: "200", MatchRegex: "Index of"}, true},
+		{"match regex miss", Config{StatusCodes: "200", MatchRegex: "Forbidden"}, false},
+		{"or: one matcher hits", Config{StatusCodes: "200", MatchRegex: "Forbidden", MatchLines: "3"}, true},
+		{"and: one matcher misses", Config{StatusCodes: "200", MatchRegex: "Forbidden", MatchLines: "3", MatchMode: MatchModeAnd}, false},
+		{"and: every matcher hits", Config{StatusCodes: "200", MatchRegex: "Index of", MatchSize: "58", MatchWords: "5", MatchMode: "AND"}, true},
+		{"filter status", Config{StatusCodes: "200", FilterStatus: "200"}, false},
+		{"filter words", Config{StatusCodes: "200", FilterWords: "5"}, false},
+		{"filter beats matcher", Config{StatusCodes: "200", MatchRegex: "Index of", FilterRegex: "db\\.sql"}, false},
+	}
+	for _, c := range cases {
+		f, err := parseFilters(&c.cfg)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if got := !f.filtered(p, page) && f.matched(p, page); got != c.want {
+			t.Errorf("%s: kept = %v, want %v (profile %+v)", c.name, got, c.want, p)
+		}
+	}
+
+	for _, cfg := range []Config{{MatchMode: "xor"}, {MatchRegex: "("}, {FilterRegex: "["}} {
+		if _, err := parseFilters(&cfg); err == nil {
+			t.Errorf("expected an error for %+v", cfg)
+		}
+	}
+}
+
+func TestParseNumberSet(t *testing.T) {
+	cases := []struct {
+		list    string
+		classes bool
+		in, out []int
+	}{
+		{"200,301", true, []int{200, 301}, []int{0, 302}},
+		{"100-250", false, []int{100, 180, 250}, []int{99, 251}},
+		{"2xx,3XX", true, []int{200, 299, 301}, []int{199, 404}},
+		{">1000", false, []int{1001, 50000}, []int{1000, 0}},
+		{">=1000, <10", false, []int{1000, 0, 9}, []int{10, 999}},
+		{"<=10", false, []int{0, 10}, []int{11}},
+		{"all", true, []int{0, 200, 999}, nil},
+	}
+	for _, c := range cases {
+		set, err := parseNumberSet(c.list, c.classes, parseNumber)
+		if err != nil {
+			t.Fatalf("%q: %v", c.list, err)
+		}
+		for _, n := range c.in {
+			if !set.has(n) {
+				t.Errorf("%q should contain %d", c.list, n)
+			}
+		}
+		for _, n := range c.out {
+			if set.has(n) {
+				t.Errorf("%q should not contain %d", c.list, n)
+			}
+		}
+	}
+
+	for _, list := range []string{"abc", "200,,301", "250-100", "10-", ">x", "6xx", "-5"} {
+		if _, err := parseNumberSet(list, true, parseNumber); err == nil {
+			t.Errorf("%q: expected an error", list)
+		}
+	}
+	if _, err := parseNumberSet("2xx", false, parseNumber); err == nil {
+		t.Error("status classes accepted for a size list")
+	}
+	if err := ValidateFilters(&Config{StatusCodes: "200,abc"}); err == nil {
+		t.Error("ValidateFilters accepted an invalid status code")
+	}
+}
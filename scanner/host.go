@@ -0,0 +1,139 @@
+package scanner
+
+import (
+	"sync"
+
+	"github.com/valyala/fasthttp"
+)
+
+// host scans one target. Every target gets its own producer, job queue,
+// workers and rate limiter, so a slow host only slows itself down.
+type host struct {
+	s     *Scanner
+	index int
+	url   string
+
+	jobs        chan Job
+	workers     sync.WaitGroup
+	rateLimiter *RateLimiter
+	throttle    *throttle
+
+	// Set when FUZZ appears in the request instead of being appended to the URL
+	templateMode bool
+	// Domain of the Host header in vhost mode, and of the names resolved in
+	// DNS-only mode
+	vhostDomain string
+	dnsDomain   string
+
+	// Producer position: the target being produced (nil for the base URL),
+	// the next job in it and whether the base URL is done.
+	mu       sync.Mutex
+	target   *RecursionTarget
+	cursor   Cursor
+	baseDone bool
+
+	// Recursion state: directories waiting to be scanned, directories already
+	// queued and the jobs handed to workers but not yet finished.
+	recursionQueue []RecursionTarget
+	recursionSeen  map[string]bool
+	seq            uint64
+	inflight       map[uint64]jobPosition
+	idleSignal     chan struct{}
+
+	// Soft-404 baselines learned by auto-calibration, per directory ("" for
+	// the base URL). A nil entry means calibration found no stable profile.
+	baselines map[string]*baseline
+}
+
+func newHost(s *Scanner, index int, url string) *host {
+	return &host{
+		s:             s,
+		index:         index,
+		url:           url,
+		recursionSeen: make(map[string]bool),
+		inflight:      make(map[uint64]jobPosition),
+		idleSignal:    make(chan struct{}, 1),
+		baselines:     make(map[string]*baseline),
+	}
+}
+
+// targetURLs returns the base URL of every target: Config.Targets, or
+// Config.URL when no target list is set.
+func targetURLs(cfg *Config) []string {
+	if len(cfg.Targets) > 0 {
+		return cfg.Targets
+	}
+	return []string{cfg.URL}
+}
+
+// run starts the producer and Config.Threads workers (Config.DNSThreads in
+// DNS-only mode) for the host and blocks until they are done.
+func (h *host) run(f *filters) {
+	cfg := h.s.config
+	h.templateMode = !h.s.keywordMode() && !cfg.Subdomain && !cfg.VHost && templateHasKeyword(cfg, h.url, DefaultKeyword)
+	if cfg.VHost {
+		// Checked by ValidateVHost when the scan starts
+		h.vhostDomain, _ = vhostDomain(cfg, h.url)
+	}
+	workers := cfg.Threads
+	if cfg.DNSOnly {
+		// Checked by ValidateDNS when the scan starts
+		h.dnsDomain, _ = targetDomain(h.url)
+		if cfg.DNSThreads > 0 {
+			workers = cfg.DNSThreads
+		}
+	}
+	h.jobs = make(chan Job, workers)
+	if cfg.AutoThrottle {
+		h.throttle = newThrottle(cfg.RateLimit)
+		h.publishRate()
+	} else {
+		h.rateLimiter = NewRateLimiter(cfg.RateLimit)
+		defer h.rateLimiter.Stop()
+	}
+
+	go func() {
+		h.produceJobs()
+		close(h.jobs)
+	}()
+
+	h.workers.Add(workers)
+	for i := 0; i < workers; i++ {
+		go h.worker(f)
+	}
+	h.workers.Wait()
+
+	h.s.statsMu.Lock()
+	h.s.stats.Hosts[h.index].Done = true
+	h.s.statsMu.Unlock()
+}
+
+// pace waits for the host's rate limiter, or for its adaptive throttle when
+// Config.AutoThrottle is set. It returns false if the scan was stopped.
+func (h *host) pace() bool {
+	h.rateLimiter.Wait()
+	return h.throttle.wait(h.s.ctx)
+}
+
+// observe feeds the outcome of a request to the adaptive throttle: resp is
+// nil when the request failed with err
+func (h *host) observe(resp *fasthttp.Response, err error) {
+	if h.throttle == nil {
+		return
+	}
+	if err != nil {
+		h.throttle.failed(err)
+	} else {
+		h.throttle.observe(resp)
+	}
+	h.publishRate()
+}
+
+// publishRate copies the effective rate of the throttle into the stats
+func (h *host) publishRate() {
+	rate, throttled := h.throttle.current()
+	h.s.statsMu.Lock()
+	h.s.stats.Hosts[h.index].Rate = rate
+	h.s.stats.Hosts[h.index].Throttled = throttled
+	h.s.statsMu.Unlock()
+}
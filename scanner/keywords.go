@@ -0,0 +1,172 @@
+package scanner
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Combination modes for multiple keyword wordlists
+const (
+	// ModeClusterbomb requests every combination of the wordlists
+	ModeClusterbomb = "clusterbomb"
+	// ModePitchfork zips the wordlists: line n of every list goes in the same request
+	ModePitchfork = "pitchfork"
+)
+
+// DefaultKeyword is the placeholder replaced by the wordlist entry
+const DefaultKeyword = "FUZZ"
+
+// KeywordWordlist binds a wordlist file to the keyword it replaces
+type KeywordWordlist struct {
+	Path    string `json:"path"`
+	Keyword string `json:"keyword"`
+}
+
+// keywordMode reports whether the scan uses keyword wordlists (Config.Wordlists)
+// instead of the single FUZZ wordlist
+func (s *Scanner) keywordMode() bool {
+	return len(s.config.Wordlists) > 0
+}
+
+// validateKeywords checks the keyword wordlist configuration before a scan
+func validateKeywords(cfg *Config) error {
+	if len(cfg.Wordlists) == 0 {
+		return nil
+	}
+	switch cfg.Mode {
+	case "", ModeClusterbomb, ModePitchfork:
+	default:
+		return fmt.Errorf("unknown mode %q (use %s or %s)", cfg.Mode, ModeClusterbomb, ModePitchfork)
+	}
+	if cfg.Subdomain {
+		return fmt.Errorf("keyword wordlists cannot be combined with subdomain mode")
+	}
+	if cfg.Recursion {
+		return fmt.Errorf("keyword wordlists cannot be combined with recursion")
+	}
+
+	seen := make(map[string]bool)
+	for _, wl := range cfg.Wordlists {
+		if wl.Keyword == "" {
+			return fmt.Errorf("wordlist %s has no keyword", wl.Path)
+		}
+		if seen[wl.Keyword] {
+			return fmt.Errorf("keyword %s is used by more than one wordlist", wl.Keyword)
+		}
+		seen[wl.Keyword] = true
+		for _, url := range targetURLs(cfg) {
+			if !templateHasKeyword(cfg, url, wl.Keyword) {
+				return fmt.Errorf("keyword %s not found in the request to %s", wl.Keyword, url)
+			}
+		}
+	}
+	return nil
+}
+
+// produceKeywords enqueues one job per combination of the keyword wordlists,
+// starting at cursor. It returns false if the scan was stopped or a wordlist
+// could not be read.
+func (h *host) produceKeywords(start Cursor) bool {
+	if h.s.config.Mode == ModePitchfork {
+		return h.producePitchfork(start)
+	}
+	var first []int
+	if len(start.Words) == len(h.s.config.Wordlists) {
+		first = start.Words
+	}
+	n := len(h.s.config.Wordlists)
+	return h.produceClusterbomb(0, make([]string, n), make([]int, n), first)
+}
+
+// producePitchfork reads every list in lockstep and stops at the end of the
+// shortest one.
+func (h *host) producePitchfork(start Cursor) bool {
+	readers := make([]*wordReader, len(h.s.config.Wordlists))
+	for k, wl := range h.s.config.Wordlists {
+		r, err := openWordlist(wl.Path)
+		if err != nil {
+			h.s.fail(err)
+			return false
+		}
+		defer r.close()
+		r.skip(start.Word)
+		readers[k] = r
+	}
+
+	for i := start.Word; ; i++ {
+		values := make([]string, len(readers))
+		for k, r := range readers {
+			if !r.next() {
+				for _, r := range readers {
+					if !h.readDone(r) {
+						return false
+					}
+				}
+				return true
+			}
+			values[k] = r.word
+		}
+		if !h.enqueue(Job{Values: values}, Cursor{Word: i + 1}) {
+			return false
+		}
+	}
+}
+
+// produceClusterbomb enqueues every combination of the lists from level k on,
+// values[:k] and idx[:k] being fixed by the outer levels. Each level streams
+// its list again for every entry of the level above, the first list being the
+// outermost loop. first is the restored cursor and only applies to the first
+// pass of every level.
+func (h *host) produceClusterbomb(k int, values []string, idx []int, first []int) bool {
+	r, err := openWordlist(h.s.config.Wordlists[k].Path)
+	if err != nil {
+		h.s.fail(err)
+		return false
+	}
+	defer r.close()
+
+	i := 0
+	if first != nil {
+		i = first[k]
+		r.skip(i)
+	}
+	last := k == len(values)-1
+	for ; r.next(); i++ {
+		values[k], idx[k] = r.word, i
+		if last {
+			next := append([]int{}, idx...)
+			next[k]++
+			if !h.enqueue(Job{Values: append([]string{}, values...)}, Cursor{Words: next}) {
+				return false
+			}
+		} else if !h.produceClusterbomb(k+1, values, idx, first) {
+			return false
+		}
+		first = nil
+	}
+	return h.readDone(r)
+}
+
+// substitute replaces every keyword in tmpl with the job's values
+func (s *Scanner) substitute(tmpl string, values []string) string {
+	for k, wl := range s.config.Wordlists {
+		tmpl = strings.ReplaceAll(tmpl, wl.Keyword, values[k])
+	}
+	return tmpl
+}
+
+// jobInputs maps each keyword to the value used in a job. It returns nil
+// when the job does not come from a request template.
+func (h *host) jobInputs(job Job) map[string]string {
+	if job.Values != nil {
+		in := make(map[string]string, len(job.Values))
+		for k, wl := range h.s.config.Wordlists {
+			in[wl.Keyword] = job.Values[k]
+		}
+		return in
+	}
+	if h.templateMode && job.Label == "" && !strings.Contains(job.URL, "://") {
+		return map[string]string{DefaultKeyword: job.URL}
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package scanner
+
+import (
+	"bubbletea-scan/internal"
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	neturl "net/url"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// maxResponseBodySize is the largest body read, as for the fasthttp client
+const maxResponseBodySize = 1024 * 1024 * 10
+
+// netHTTPTransport sends requests with net/http, which negotiates HTTP/2
+// over TLS through ALPN and falls back to HTTP/1.1 otherwise
+type netHTTPTransport struct {
+	client  *http.Client
+	proxies *internal.ProxyDialer // nil without Config.Proxy
+}
+
+// proxyKey is the context key of the proxy picked for a request
+type proxyKey struct{}
+
+func newNetHTTPTransport(cfg *Config) (*netHTTPTransport, error) {
+	tlsConfig, err := newTLSConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	tr := &http.Transport{
+		ForceAttemptHTTP2:   true,
+		MaxIdleConnsPerHost: cfg.Threads * 2,
+		IdleConnTimeout:     30 * time.Second,
+		TLSHandshakeTimeout: time.Duration(cfg.Timeout) * time.Second,
+		// Bodies are compared as received, like with fasthttp
+		DisableCompression: true,
+		TLSClientConfig:    tlsConfig,
+	}
+	// Do picks the proxy of every request. net/http speaks to HTTP proxies
+	// itself; SOCKS5 connections are dialed by the proxy layer, which
+	// resolves host names locally for socks5 as with fasthttp.
+	var proxies *internal.ProxyDialer
+	if cfg.Proxy != "" {
+		timeout := time.Duration(cfg.Timeout) * time.Second
+		proxies, err = internal.NewProxyDialer(cfg.Proxy, timeout)
+		if err != nil {
+			return nil, err
+		}
+		tr.Proxy = func(r *http.Request) (*neturl.URL, error) {
+			if p, _ := r.Context().Value(proxyKey{}).(*neturl.URL); p != nil && p.Scheme == "http" {
+				return p, nil
+			}
+			return nil, nil
+		}
+		direct := &net.Dialer{Timeout: timeout}
+		tr.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			if p, _ := ctx.Value(proxyKey{}).(*neturl.URL); p != nil && p.Scheme != "http" {
+				return proxies.TunnelVia(p, addr)
+			}
+			return direct.DialContext(ctx, network, addr)
+		}
+	}
+	return &netHTTPTransport{proxies: proxies, client: &http.Client{
+		Transport: tr,
+		Timeout:   time.Duration(cfg.Timeout) * time.Second,
+		// Redirects are reported, or followed by the scanner itself
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}}, nil
+}
+
+func (t *netHTTPTransport) Do(req *fasthttp.Request, resp *fasthttp.Response, deadline time.Time) error {
+	ctx := context.Background()
+	if !deadline.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, deadline)
+		defer cancel()
+	}
+	if t.proxies != nil {
+		ctx = context.WithValue(ctx, proxyKey{}, t.proxies.Next())
+	}
+
+	var body io.Reader
+	if b := req.Body(); len(b) > 0 {
+		body = bytes.NewReader(b)
+	}
+	hreq, err := http.NewRequestWithContext(ctx, string(req.Header.Method()), req.URI().String(), body)
+	if err != nil {
+		return err
+	}
+	for key, value := range req.Header.All() {
+		switch k := string(key); k {
+		case fasthttp.HeaderHost:
+			hreq.Host = string(value)
+		case fasthttp.HeaderContentLength, fasthttp.HeaderConnection, fasthttp.HeaderTransferEncoding:
+			// Managed by net/http
+		default:
+			hreq.Header.Add(k, string(value))
+		}
+	}
+
+	hresp, err := t.client.Do(hreq)
+	if err != nil {
+		return err
+	}
+	defer hresp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(hresp.Body, maxResponseBodySize+1))
+	if err != nil {
+		return err
+	}
+	if len(data) > maxResponseBodySize {
+		return fasthttp.ErrBodyTooLarge
+	}
+
+	resp.Reset()
+	resp.SetStatusCode(hresp.StatusCode)
+	for key, values := range hresp.Header {
+		if key == fasthttp.HeaderContentLength || key == fasthttp.HeaderTransferEncoding {
+			continue
+		}
+		for _, value := range values {
+			resp.Header.Add(key, value)
+		}
+	}
+	resp.SetBody(data)
+	return nil
+}
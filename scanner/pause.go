@@ -0,0 +1,117 @@
+package scanner
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// gate is the barrier used to pause the producer and the workers. While the
+// gate is closed, goroutines block in wait and keep their position, so the
+// scan continues exactly where it stopped once the gate opens again.
+type gate struct {
+	mu       sync.Mutex
+	paused   bool
+	resume   chan struct{}
+	pausedAt time.Time
+	// total time spent paused, excluding the current pause
+	pausedFor time.Duration
+}
+
+// wait blocks while the gate is paused. It returns false if ctx is cancelled.
+func (g *gate) wait(ctx context.Context) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	g.mu.Lock()
+	if !g.paused {
+		g.mu.Unlock()
+		return true
+	}
+	resume := g.resume
+	g.mu.Unlock()
+
+	select {
+	case <-resume:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (g *gate) pause() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.paused {
+		return
+	}
+	g.paused = true
+	g.resume = make(chan struct{})
+	g.pausedAt = time.Now()
+}
+
+func (g *gate) unpause() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if !g.paused {
+		return
+	}
+	g.paused = false
+	g.pausedFor += time.Since(g.pausedAt)
+	close(g.resume)
+}
+
+func (g *gate) isPaused() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.paused
+}
+
+// pausedDuration returns the total time spent paused, including the current pause
+func (g *gate) pausedDuration() time.Duration {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	d := g.pausedFor
+	if g.paused {
+		d += time.Since(g.pausedAt)
+	}
+	return d
+}
+
+// Pause holds the producer and the workers at a barrier. Requests already in
+// flight complete; no job is dropped. Pause may be called before Run.
+func (s *Scanner) Pause() {
+	s.clearBreaker()
+	s.gate.pause()
+	s.publishProgress()
+}
+
+// Resume releases a paused scan, continuing from the producer's position.
+// It also releases a pause caused by the circuit breaker.
+func (s *Scanner) Resume() {
+	s.clearBreaker()
+	s.gate.unpause()
+}
+
+// clearBreaker hands a pause caused by the circuit breaker over to the caller
+func (s *Scanner) clearBreaker() {
+	s.breaker.reset()
+	s.statsMu.Lock()
+	s.stats.Breaker = ""
+	s.statsMu.Unlock()
+}
+
+// Paused reports whether the scan is currently paused
+func (s *Scanner) Paused() bool {
+	return s.gate.isPaused()
+}
+
+// activeDuration is the time spent scanning, excluding pauses and including
+// the time recorded in a restored checkpoint
+func (s *Scanner) activeDuration() time.Duration {
+	if s.startTime.IsZero() {
+		return s.priorElapsed
+	}
+	return s.priorElapsed + time.Since(s.startTime) - s.gate.pausedDuration()
+}
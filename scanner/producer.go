@@ -0,0 +1,248 @@
+package scanner
+
+import "strings"
+
+func (h *host) produceJobs() {
+	// A restored scan may already be past the base URL, in the middle of a
+	// discovered directory.
+	if !h.baseDone {
+		if !h.produceTarget(nil) {
+			return
+		}
+	} else if h.target != nil {
+		if !h.produceTarget(h.target) {
+			return
+		}
+	}
+
+	if !h.s.config.Recursion || h.s.config.Subdomain || h.s.config.VHost || h.s.config.DNSOnly {
+		return
+	}
+
+	// Scan discovered directories until the queue is drained and every
+	// worker is idle (no job left that could discover another directory).
+	for {
+		target, ok := h.nextRecursion()
+		if !ok {
+			return
+		}
+		if !h.produceTarget(&target) {
+			return
+		}
+	}
+}
+
+// produceTarget enqueues the jobs for the base URL (target == nil) or for a
+// discovered directory, starting at the current cursor. The cursor is reset
+// once the target is exhausted. It returns false if the scan was stopped or
+// the wordlist could not be read.
+func (h *host) produceTarget(target *RecursionTarget) bool {
+	h.mu.Lock()
+	h.target = target
+	start := h.cursor
+	h.mu.Unlock()
+
+	// Learn the soft-404 profile before any job of the target is requested.
+	// Vhost mode always learns the response of a random virtual host.
+	if (h.s.config.AutoCalibrate || h.s.config.VHost) && !h.s.config.Subdomain && !h.s.config.DNSOnly && !h.calibrate(target) {
+		return false
+	}
+
+	if h.s.keywordMode() {
+		if !h.produceKeywords(start) {
+			return false
+		}
+	} else if !h.produceWords(target, start) {
+		return false
+	}
+
+	h.mu.Lock()
+	h.target = nil
+	h.cursor = Cursor{}
+	h.baseDone = true
+	h.mu.Unlock()
+	return true
+}
+
+// produceWords streams the single FUZZ wordlist and enqueues its jobs for a
+// target, starting at cursor. It returns false if the scan was stopped or the
+// wordlist could not be read.
+func (h *host) produceWords(target *RecursionTarget, start Cursor) bool {
+	var extensions []string
+	if h.s.config.Extensions != "" {
+		extensions = strings.Split(h.s.config.Extensions, ",")
+	}
+
+	words, err := openWordlist(h.s.config.Wordlist)
+	if err != nil {
+		h.s.fail(err)
+		return false
+	}
+	defer words.close()
+	words.skip(start.Word)
+
+	for w := start.Word; words.next(); w++ {
+		word := words.word
+
+		// If subdomain mode, enqueue the subdomain candidate as a job that
+		// will be combined with the target host in the worker.
+		if h.s.config.Subdomain {
+			if h.s.config.SubdomainPaths {
+				if !h.produceSubdomainPaths(word, w, start.Path) {
+					return false
+				}
+				start.Path = 0
+			} else if !h.enqueue(Job{Label: word, Depth: 0}, Cursor{Word: w + 1}) {
+				return false
+			}
+			continue
+		}
+
+		// Vhost and DNS-only modes: one job per label, sent to the target URL
+		// itself or resolved
+		if h.s.config.VHost || h.s.config.DNSOnly {
+			if !h.enqueue(Job{Label: word}, Cursor{Word: w + 1}) {
+				return false
+			}
+			continue
+		}
+
+		depth, dir := 0, ""
+		if target != nil {
+			word = strings.TrimLeft(word, "/")
+			if word == "" {
+				continue
+			}
+			word = target.URL + word
+			depth, dir = target.Depth, target.URL
+		}
+
+		// Normal path fuzzing: the bare word, then one job per extension
+		for e := start.Ext; e <= len(extensions); e++ {
+			url := word
+			if e > 0 {
+				url += extensions[e-1]
+			}
+			if !h.enqueue(Job{URL: url, Depth: depth, dir: dir}, Cursor{Word: w, Ext: e + 1}) {
+				return false
+			}
+		}
+		start.Ext = 0
+	}
+	return h.readDone(words)
+}
+
+// produceSubdomainPaths enqueues the cartesian product of one label (entry w
+// of the wordlist) with every path of a second pass over the same wordlist,
+// starting at path index first.
+func (h *host) produceSubdomainPaths(label string, w, first int) bool {
+	paths, err := openWordlist(h.s.config.Wordlist)
+	if err != nil {
+		h.s.fail(err)
+		return false
+	}
+	defer paths.close()
+	paths.skip(first)
+
+	for p := first; paths.next(); p++ {
+		if !h.enqueue(Job{Label: label, Path: paths.word, Depth: 0}, Cursor{Word: w, Path: p + 1}) {
+			return false
+		}
+	}
+	return h.readDone(paths)
+}
+
+// readDone reports whether a wordlist was read to the end without error. A
+// read error is recorded on the scanner and stops the producer.
+func (h *host) readDone(r *wordReader) bool {
+	if err := r.err(); err != nil {
+		h.s.fail(err)
+		return false
+	}
+	return true
+}
+
+// enqueue hands a job to the workers and moves the cursor to next. It blocks
+// while the scan is paused and returns false if the scan was stopped.
+func (h *host) enqueue(job Job, next Cursor) bool {
+	if !h.s.gate.wait(h.s.ctx) {
+		return false
+	}
+
+	// The job starts where the previous one left the cursor
+	h.mu.Lock()
+	h.seq++
+	job.seq = h.seq
+	h.inflight[job.seq] = jobPosition{target: h.target, cursor: h.cursor}
+	h.mu.Unlock()
+
+	select {
+	case h.jobs <- job:
+		h.mu.Lock()
+		h.cursor = next
+		h.mu.Unlock()
+		return true
+	case <-h.s.ctx.Done():
+		h.jobDone(job)
+		return false
+	}
+}
+
+// jobDone marks a job as finished and wakes the producer when no job is left in flight.
+func (h *host) jobDone(job Job) {
+	h.mu.Lock()
+	delete(h.inflight, job.seq)
+	idle := len(h.inflight) == 0
+	h.mu.Unlock()
+
+	if idle {
+		select {
+		case h.idleSignal <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// queueRecursion schedules a discovered directory for scanning, once per URL.
+func (h *host) queueRecursion(dirURL string, depth int) {
+	h.mu.Lock()
+	if h.recursionSeen[dirURL] {
+		h.mu.Unlock()
+		return
+	}
+	h.recursionSeen[dirURL] = true
+	h.recursionQueue = append(h.recursionQueue, RecursionTarget{URL: dirURL, Depth: depth})
+	h.mu.Unlock()
+
+	h.s.statsMu.Lock()
+	h.s.stats.RecursionCount++
+	h.s.stats.RecursionActive = true
+	h.s.statsMu.Unlock()
+}
+
+// nextRecursion returns the next queued directory. When the queue is empty it
+// waits for in-flight jobs, since they may still discover new directories, and
+// returns false once nothing is left to do or the scan is stopped.
+func (h *host) nextRecursion() (RecursionTarget, bool) {
+	for {
+		h.mu.Lock()
+		if len(h.recursionQueue) > 0 {
+			target := h.recursionQueue[0]
+			h.recursionQueue = h.recursionQueue[1:]
+			h.mu.Unlock()
+			return target, true
+		}
+		idle := len(h.inflight) == 0
+		h.mu.Unlock()
+
+		if idle {
+			return RecursionTarget{}, false
+		}
+
+		select {
+		case <-h.idleSignal:
+		case <-h.s.ctx.Done():
+			return RecursionTarget{}, false
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package scanner
+
+import "time"
+
+// RateLimiter is a simple token bucket refilled at a fixed requests-per-second rate
+type RateLimiter struct {
+	tokens chan struct{}
+	ticker *time.Ticker
+	stop   chan struct{}
+}
+
+// NewRateLimiter returns a limiter allowing rps requests per second, or nil
+// (no limiting) when rps <= 0. A nil *RateLimiter is safe to use.
+func NewRateLimiter(rps int) *RateLimiter {
+	if rps <= 0 {
+		return nil // No rate limiting
+	}
+
+	rl := &RateLimiter{
+		tokens: make(chan struct{}, rps),
+		ticker: time.NewTicker(time.Second / time.Duration(rps)),
+		stop:   make(chan struct{}),
+	}
+
+	// Fill initial tokens
+	for i := 0; i < rps; i++ {
+		rl.tokens <- struct{}{}
+	}
+
+	// Refill tokens
+	go func() {
+		for {
+			select {
+			case <-rl.ticker.C:
+				select {
+				case rl.tokens <- struct{}{}:
+				default:
+				}
+			case <-rl.stop:
+				return
+			}
+		}
+	}()
+
+	return rl
+}
+
+// Wait blocks until a token is available
+func (rl *RateLimiter) Wait() {
+	if rl == nil {
+		return
+	}
+	<-rl.tokens
+}
+
+// Stop releases the refill goroutine
+func (rl *RateLimiter) Stop() {
+	if rl == nil {
+		return
+	}
+	close(rl.stop)
+	rl.ticker.Stop()
+}
@@ -0,0 +1,127 @@
+package scanner
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net/textproto"
+	"os"
+	"strings"
+)
+
+// RawRequest is a raw HTTP/1.1 request (for example copied from Burp
+// Repeater) split into the parts a scan template is made of.
+type RawRequest struct {
+	Method    string
+	URL       string
+	UserAgent string
+	Cookies   string
+	// Remaining header lines as "Name: Value"
+	Headers []string
+	Body    string
+}
+
+// skippedRequestHeaders are computed by the client or would break the scan:
+// Content-Length changes with every substituted body, the body is sent as is
+// rather than chunked, and compressed bodies cannot be matched by size, lines
+// or regex.
+var skippedRequestHeaders = map[string]bool{
+	"Host":              true,
+	"Content-Length":    true,
+	"Transfer-Encoding": true,
+	"Accept-Encoding":   true,
+	"Cookie":            true,
+	"User-Agent":        true,
+}
+
+// LoadRawRequest reads and parses a raw request file. See ParseRawRequest.
+func LoadRawRequest(path, scheme string) (*RawRequest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	req, err := ParseRawRequest(data, scheme)
+	if err != nil {
+		return nil, fmt.Errorf("invalid request file %s: %w", path, err)
+	}
+	return req, nil
+}
+
+// ParseRawRequest parses a raw HTTP/1.1 request. The URL is built from scheme,
+// the Host header and the request target (an absolute-form target is used as
+// is). Keywords such as FUZZ may appear anywhere and are kept untouched.
+// Line endings are normalized in the head only: the body is kept byte for
+// byte, so CRLF multipart bodies stay valid.
+func ParseRawRequest(data []byte, scheme string) (*RawRequest, error) {
+	// The head ends at the first empty line, written with CRLF or LF
+	end, sep := bytes.Index(data, []byte("\r\n\r\n")), 4
+	if lf := bytes.Index(data, []byte("\n\n")); lf >= 0 && (end < 0 || lf < end) {
+		end, sep = lf, 2
+	}
+	head, body := data, []byte(nil)
+	if end >= 0 {
+		head, body = data[:end], data[end+sep:]
+	}
+	head = bytes.ReplaceAll(head, []byte("\r\n"), []byte("\n"))
+
+	sc := bufio.NewScanner(bytes.NewReader(head))
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	if !sc.Scan() {
+		return nil, fmt.Errorf("empty request")
+	}
+	parts := strings.Fields(sc.Text())
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("malformed request line %q", sc.Text())
+	}
+
+	req := &RawRequest{Method: parts[0]}
+	target := parts[1]
+	host := ""
+	for sc.Scan() {
+		line := sc.Text()
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("malformed header line %q", line)
+		}
+		name = textproto.CanonicalMIMEHeaderKey(strings.TrimSpace(name))
+		value = strings.TrimSpace(value)
+
+		switch name {
+		case "Host":
+			host = value
+		case "Cookie":
+			if req.Cookies != "" {
+				req.Cookies += "; "
+			}
+			req.Cookies += value
+		case "User-Agent":
+			req.UserAgent = value
+		}
+		if !skippedRequestHeaders[name] {
+			req.Headers = append(req.Headers, name+": "+value)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+
+	if strings.Contains(target, "://") {
+		req.URL = target
+	} else {
+		if host == "" {
+			return nil, fmt.Errorf("missing Host header")
+		}
+		if scheme == "" {
+			scheme = "https"
+		}
+		req.URL = scheme + "://" + host + target
+	}
+
+	// Editors usually end the file with a newline that is not part of the
+	// body; a CRLF ending belongs to a captured body and is kept
+	req.Body = string(body)
+	if !strings.HasSuffix(req.Body, "\r\n") {
+		req.Body = strings.TrimSuffix(req.Body, "\n")
+	}
+	return req, nil
+}
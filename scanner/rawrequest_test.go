@@ -0,0 +1,60 @@
+package scanner
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseRawRequest(t *testing.T) {
+	raw := "POST /api/FUZZ?x=1 HTTP/1.1\r\n" +
+		"Host: example.com:8443\r\n" +
+		"User-Agent: Mozilla/5.0\r\n" +
+		"Cookie: session=abc\r\n" +
+		"Content-Type: application/json\r\n" +
+		"Content-Length: 15\r\n" +
+		"Accept-Encoding: gzip, deflate\r\n" +
+		"X-Token: FUZZ\r\n" +
+		"\r\n" +
+		`{"id":"FUZZ"}` + "\n"
+
+	req, err := ParseRawRequest([]byte(raw), "https")
+	if err != nil {
+		t.Fatalf("ParseRawRequest: %v", err)
+	}
+	want := &RawRequest{
+		Method:    "POST",
+		URL:       "https://example.com:8443/api/FUZZ?x=1",
+		UserAgent: "Mozilla/5.0",
+		Cookies:   "session=abc",
+		Headers:   []string{"Content-Type: application/json", "X-Token: FUZZ"},
+		Body:      `{"id":"FUZZ"}`,
+	}
+	if !reflect.DeepEqual(req, want) {
+		t.Fatalf("got %+v\nwant %+v", req, want)
+	}
+
+	// A multipart body captured with CRLF line endings is kept byte for byte
+	body := "--B\r\nContent-Disposition: form-data; name=\"file\"\r\n\r\nFUZZ\r\n--B--\r\n"
+	raw = "POST /upload HTTP/1.1\r\n" +
+		"Host: example.com\r\n" +
+		"Content-Type: multipart/form-data; boundary=B\r\n" +
+		"Transfer-Encoding: chunked\r\n" +
+		"\r\n" + body
+	req, err = ParseRawRequest([]byte(raw), "https")
+	if err != nil {
+		t.Fatalf("ParseRawRequest: %v", err)
+	}
+	if req.Body != body {
+		t.Errorf("body = %q, want %q", req.Body, body)
+	}
+	if want := []string{"Content-Type: multipart/form-data; boundary=B"}; !reflect.DeepEqual(req.Headers, want) {
+		t.Errorf("headers = %q, want %q", req.Headers, want)
+	}
+
+	if _, err := ParseRawRequest([]byte("GET / HTTP/1.1\n\n"), "http"); err == nil {
+		t.Error("expected an error for a request without Host")
+	}
+	if _, err := ParseRawRequest([]byte("GET\n"), "http"); err == nil {
+		t.Error("expected an error for a malformed request line")
+	}
+}
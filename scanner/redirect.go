@@ -0,0 +1,81 @@
+package scanner
+
+import (
+	neturl "net/url"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// defaultMaxRedirects is used when Config.MaxRedirects is not set
+const defaultMaxRedirects = 5
+
+// isRedirect reports whether status is a 3xx redirect
+func isRedirect(status int) bool {
+	return status >= 300 && status < 400
+}
+
+// resolveLocation returns the Location header resolved against the request
+// URL, or "" when there is none
+func resolveLocation(rawURL, location string) string {
+	if location == "" {
+		return ""
+	}
+	base, err := neturl.Parse(rawURL)
+	if err != nil {
+		return location
+	}
+	loc, err := neturl.Parse(location)
+	if err != nil {
+		return location
+	}
+	return base.ResolveReference(loc).String()
+}
+
+// followRedirects requests result.Location and the redirects after it, up to
+// Config.MaxRedirects hops, and records the chain and the status and size of
+// the last response. It stops early on a loop or a failed request. req is the
+// request that produced result; it is not modified.
+func (h *host) followRedirects(req *fasthttp.Request, result *Result) {
+	maxHops := h.s.config.MaxRedirects
+	if maxHops <= 0 {
+		maxHops = defaultMaxRedirects
+	}
+
+	next := fasthttp.AcquireRequest()
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseRequest(next)
+	defer fasthttp.ReleaseResponse(resp)
+	req.CopyTo(next)
+
+	visited := map[string]bool{result.Path: true}
+	status, location := result.Status, result.Location
+	for hop := 0; hop < maxHops && isRedirect(status) && location != "" && !visited[location]; hop++ {
+		// Like a browser: 301/302/303 continue with a GET, 307/308 keep the
+		// method and body. The Host header follows the new URL.
+		if status != 307 && status != 308 && !next.Header.IsGet() && !next.Header.IsHead() {
+			next.Header.SetMethod(fasthttp.MethodGet)
+			next.ResetBody()
+			next.Header.Del("Content-Type")
+		}
+		next.Header.Del("Host")
+		next.SetRequestURI(location)
+
+		if !h.s.gate.wait(h.s.ctx) || !h.pace() {
+			return
+		}
+		err := h.s.transport.Do(next, resp, time.Time{})
+		h.observe(resp, err)
+		if err != nil {
+			return
+		}
+
+		visited[location] = true
+		result.RedirectChain = append(result.RedirectChain, location)
+		result.FinalStatus = resp.StatusCode()
+		result.FinalSize = len(resp.Body())
+
+		status = resp.StatusCode()
+		location = resolveLocation(location, string(resp.Header.Peek("Location")))
+	}
+}
@@ -0,0 +1,40 @@
+package scanner
+
+import (
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// newReplayTransport builds the transport that resends matching requests
+// through Config.ReplayProxy, or nil when no replay proxy is set. It uses the
+// same backend and settings as the scan, with the replay proxy instead of
+// Config.Proxy. Certificates are not verified: the replay proxy is an
+// intercepting proxy such as Burp, which presents its own.
+func newReplayTransport(cfg *Config) (Transport, error) {
+	if cfg.ReplayProxy == "" {
+		return nil, nil
+	}
+	replay := *cfg
+	replay.Proxy = cfg.ReplayProxy
+	replay.NoTLS = true
+	return NewTransport(&replay)
+}
+
+// replay resends req, which produced a result, through the replay proxy so
+// that the hit shows up in the proxy's history (for example Burp's site map).
+// The response is discarded; a failure does not affect the scan and is only
+// counted in Stats.
+func (h *host) replay(req *fasthttp.Request) {
+	if h.s.replay == nil {
+		return
+	}
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(resp)
+	if err := h.s.replay.Do(req, resp, time.Now().Add(time.Duration(h.s.config.Timeout)*time.Second)); err != nil {
+		h.s.statsMu.Lock()
+		h.s.stats.ReplayFailed++
+		h.s.stats.ReplayError = err.Error()
+		h.s.statsMu.Unlock()
+	}
+}
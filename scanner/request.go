@@ -0,0 +1,68 @@
+package scanner
+
+import (
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+// requestTemplate returns every part of the request a keyword may appear in:
+// the target URL, method, user agent, cookies, body and the raw header lines.
+func requestTemplate(cfg *Config, url string) []string {
+	fields := []string{url, cfg.Method, cfg.UserAgent, cfg.Cookies, cfg.Data}
+	return append(fields, cfg.Headers...)
+}
+
+// templateHasKeyword reports whether keyword appears anywhere in the request to url
+func templateHasKeyword(cfg *Config, url, keyword string) bool {
+	for _, field := range requestTemplate(cfg, url) {
+		if strings.Contains(field, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// fill replaces the keywords of a request template field with the job's values.
+// In single-wordlist mode FUZZ is replaced by the word, except for recursion and
+// subdomain jobs which do not use the template.
+func (h *host) fill(tmpl string, job Job) string {
+	if job.Values != nil {
+		return h.s.substitute(tmpl, job.Values)
+	}
+	if h.templateMode && job.Label == "" && !strings.Contains(job.URL, "://") {
+		return strings.ReplaceAll(tmpl, DefaultKeyword, job.URL)
+	}
+	return tmpl
+}
+
+// prepareRequest resets req and fills it for job: method, headers, cookies
+// and body may all carry keywords.
+func (h *host) prepareRequest(req *fasthttp.Request, job Job, url string) {
+	req.Reset()
+	req.SetRequestURI(url)
+	req.Header.SetMethod(h.fill(h.s.config.Method, job))
+	req.Header.Set("User-Agent", h.fill(h.s.config.UserAgent, job))
+
+	// Add cookies if provided
+	if h.s.config.Cookies != "" {
+		req.Header.Set("Cookie", h.fill(h.s.config.Cookies, job))
+	}
+
+	for _, line := range h.s.config.Headers {
+		parts := strings.SplitN(h.fill(line, job), ":", 2)
+		if len(parts) == 2 {
+			req.Header.Set(strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1]))
+		}
+	}
+
+	if h.s.config.Data != "" {
+		req.SetBodyString(h.fill(h.s.config.Data, job))
+	}
+
+	// Vhost mode: connect to the target but ask for another virtual host
+	if vhost := h.vhost(job); vhost != "" {
+		req.UseHostHeader = true
+		req.Header.SetHost(vhost)
+	}
+}
@@ -0,0 +1,351 @@
+package scanner
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/netip"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// DNS record types accepted by Config.DNSTypes
+const (
+	RecordA     = "A"
+	RecordAAAA  = "AAAA"
+	RecordCNAME = "CNAME"
+)
+
+const (
+	// defaultDNSTimeout is used when Config.DNSTimeout is not set
+	defaultDNSTimeout = 2 * time.Second
+	// maxResolverFailures is the number of consecutive failures after which
+	// a resolver is skipped, as long as a healthier one is left
+	maxResolverFailures = 5
+	// resolvConf lists the system resolvers used when none is configured
+	resolvConf = "/etc/resolv.conf"
+)
+
+var recordTypes = map[string]dnsmessage.Type{
+	RecordA:     dnsmessage.TypeA,
+	RecordAAAA:  dnsmessage.TypeAAAA,
+	RecordCNAME: dnsmessage.TypeCNAME,
+}
+
+// DNSRecord is one answer of a DNS lookup
+type DNSRecord struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// String renders a record as "A 192.0.2.1"
+func (r DNSRecord) String() string {
+	return r.Type + " " + r.Value
+}
+
+// nameserver is a resolver address with its count of consecutive failures
+type nameserver struct {
+	addr  string
+	fails atomic.Int32
+}
+
+// resolver sends DNS queries straight to a list of nameservers, in rotation.
+// A failed query (timeout, SERVFAIL, REFUSED...) is sent again to the next
+// nameserver, and nameservers that keep failing are skipped.
+type resolver struct {
+	servers []*nameserver
+	next    atomic.Uint32
+	types   []string
+	timeout time.Duration
+	retries int
+}
+
+// ValidateDNS checks the DNS-only mode settings of cfg
+func ValidateDNS(cfg *Config) error {
+	if !cfg.DNSOnly {
+		return nil
+	}
+	switch {
+	case cfg.Subdomain:
+		return fmt.Errorf("DNS mode cannot be combined with subdomain mode")
+	case cfg.VHost:
+		return fmt.Errorf("DNS mode cannot be combined with vhost mode")
+	case len(cfg.Wordlists) > 0:
+		return fmt.Errorf("DNS mode cannot be combined with keyword wordlists")
+	case cfg.Recursion:
+		return fmt.Errorf("DNS mode cannot be combined with recursion")
+	}
+	for _, url := range targetURLs(cfg) {
+		if _, err := targetDomain(url); err != nil {
+			return err
+		}
+	}
+	_, err := newResolver(cfg)
+	return err
+}
+
+func newResolver(cfg *Config) (*resolver, error) {
+	r := &resolver{
+		timeout: time.Duration(cfg.DNSTimeout) * time.Millisecond,
+		retries: max(cfg.Retries, 0),
+	}
+	if r.timeout <= 0 {
+		r.timeout = defaultDNSTimeout
+	}
+
+	types, err := parseRecordTypes(cfg.DNSTypes)
+	if err != nil {
+		return nil, err
+	}
+	r.types = types
+
+	list := cfg.Resolvers
+	if strings.TrimSpace(list) == "" {
+		if list, err = systemResolvers(resolvConf); err != nil {
+			return nil, err
+		}
+	}
+	for _, addr := range strings.Split(list, ",") {
+		addr = strings.TrimSpace(addr)
+		if addr == "" {
+			continue
+		}
+		server, err := parseResolver(addr)
+		if err != nil {
+			return nil, err
+		}
+		r.servers = append(r.servers, &nameserver{addr: server})
+	}
+	if len(r.servers) == 0 {
+		return nil, fmt.Errorf("empty resolver list")
+	}
+	return r, nil
+}
+
+// parseRecordTypes reads a comma separated list of record types; A is the
+// default
+func parseRecordTypes(list string) ([]string, error) {
+	if strings.TrimSpace(list) == "" {
+		return []string{RecordA}, nil
+	}
+	var types []string
+	seen := make(map[string]bool)
+	for _, t := range strings.Split(list, ",") {
+		t = strings.ToUpper(strings.TrimSpace(t))
+		if t == "" || seen[t] {
+			continue
+		}
+		if _, ok := recordTypes[t]; !ok {
+			return nil, fmt.Errorf("unsupported record type %q (use A, AAAA or CNAME)", t)
+		}
+		seen[t] = true
+		types = append(types, t)
+	}
+	return types, nil
+}
+
+// parseResolver normalizes a resolver address to ip:port, port 53 by default
+func parseResolver(addr string) (string, error) {
+	if ip, err := netip.ParseAddr(strings.Trim(addr, "[]")); err == nil {
+		return netip.AddrPortFrom(ip, 53).String(), nil
+	}
+	ap, err := netip.ParseAddrPort(addr)
+	if err != nil {
+		return "", fmt.Errorf("invalid resolver %q: use an IP address with an optional port", addr)
+	}
+	return ap.String(), nil
+}
+
+// systemResolvers returns the nameservers of a resolv.conf file as a comma
+// separated list
+func systemResolvers(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("no resolver given and cannot read %s: %w", path, err)
+	}
+	var servers []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if fields := strings.Fields(line); len(fields) >= 2 && fields[0] == "nameserver" {
+			servers = append(servers, fields[1])
+		}
+	}
+	if len(servers) == 0 {
+		return "", fmt.Errorf("no resolver given and none found in %s", path)
+	}
+	return strings.Join(servers, ","), nil
+}
+
+// pick returns the next nameserver of the rotation, skipping the ones that
+// failed maxResolverFailures times in a row unless every one did
+func (r *resolver) pick() *nameserver {
+	start := r.next.Add(1) - 1
+	for i := range uint32(len(r.servers)) {
+		ns := r.servers[int((start+i)%uint32(len(r.servers)))]
+		if ns.fails.Load() < maxResolverFailures {
+			return ns
+		}
+	}
+	return r.servers[int(start%uint32(len(r.servers)))]
+}
+
+// resolve looks up every configured record type of name. It returns the
+// records found (none for a name that does not exist), the number of queries
+// sent for the type that needed the most attempts, and the last error when a
+// type could not be resolved by any attempt.
+func (r *resolver) resolve(ctx context.Context, name string) ([]DNSRecord, int, error) {
+	var records []DNSRecord
+	seen := make(map[DNSRecord]bool)
+	attempts := 0
+	for _, t := range r.types {
+		found, n, err := r.lookup(ctx, name, t)
+		attempts = max(attempts, n)
+		if err != nil {
+			return records, attempts, err
+		}
+		for _, rec := range found {
+			if !seen[rec] {
+				seen[rec] = true
+				records = append(records, rec)
+			}
+		}
+	}
+	return records, attempts, nil
+}
+
+// lookup queries one record type of name, moving on to the next nameserver
+// after every failure, up to retries times
+func (r *resolver) lookup(ctx context.Context, name, recordType string) ([]DNSRecord, int, error) {
+	var lastErr error
+	for attempt := 1; attempt <= r.retries+1; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return nil, attempt - 1, err
+		}
+		ns := r.pick()
+		records, err := r.query(ctx, ns.addr, name, recordType)
+		if err == nil {
+			ns.fails.Store(0)
+			return records, attempt, nil
+		}
+		ns.fails.Add(1)
+		lastErr = err
+	}
+	return nil, r.retries + 1, lastErr
+}
+
+// query sends one question to server over UDP, and again over TCP when the
+// answer is truncated. Only answers of recordType are returned; a
+// non-existent name is not an error.
+func (r *resolver) query(ctx context.Context, server, name, recordType string) ([]DNSRecord, error) {
+	qname, err := dnsmessage.NewName(strings.TrimSuffix(name, ".") + ".")
+	if err != nil {
+		return nil, &net.DNSError{Err: err.Error(), Name: name, Server: server}
+	}
+	qtype := recordTypes[recordType]
+	msg := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: uint16(rand.N(1 << 16)), RecursionDesired: true},
+		Questions: []dnsmessage.Question{{Name: qname, Type: qtype, Class: dnsmessage.ClassINET}},
+	}
+	packet, err := msg.Pack()
+	if err != nil {
+		return nil, &net.DNSError{Err: err.Error(), Name: name, Server: server}
+	}
+
+	reply, err := r.exchange(ctx, "udp", server, packet, msg.ID)
+	if err == nil && reply.Truncated {
+		reply, err = r.exchange(ctx, "tcp", server, packet, msg.ID)
+	}
+	if err != nil {
+		return nil, &net.DNSError{Err: err.Error(), Name: name, Server: server,
+			IsTimeout: isTimeout(err), IsTemporary: true, UnwrapErr: err}
+	}
+
+	switch reply.RCode {
+	case dnsmessage.RCodeSuccess:
+	case dnsmessage.RCodeNameError:
+		return nil, nil
+	default:
+		return nil, &net.DNSError{Err: "server answered " + strings.TrimPrefix(reply.RCode.String(), "RCode"),
+			Name: name, Server: server, IsTemporary: true}
+	}
+
+	var records []DNSRecord
+	for _, answer := range reply.Answers {
+		if answer.Header.Type != qtype {
+			continue
+		}
+		switch body := answer.Body.(type) {
+		case *dnsmessage.AResource:
+			records = append(records, DNSRecord{Type: RecordA, Value: netip.AddrFrom4(body.A).String()})
+		case *dnsmessage.AAAAResource:
+			records = append(records, DNSRecord{Type: RecordAAAA, Value: netip.AddrFrom16(body.AAAA).String()})
+		case *dnsmessage.CNAMEResource:
+			records = append(records, DNSRecord{Type: RecordCNAME, Value: strings.TrimSuffix(body.CNAME.String(), ".")})
+		}
+	}
+	return records, nil
+}
+
+// exchange sends packet to server and returns the reply with the given ID.
+// Stray UDP replies with another ID are ignored.
+func (r *resolver) exchange(ctx context.Context, network, server string, packet []byte, id uint16) (*dnsmessage.Message, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, network, server)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+
+	if network == "tcp" {
+		// Messages are prefixed with their length over TCP
+		framed := binary.BigEndian.AppendUint16(nil, uint16(len(packet)))
+		if _, err := conn.Write(append(framed, packet...)); err != nil {
+			return nil, err
+		}
+		var size [2]byte
+		if _, err := io.ReadFull(conn, size[:]); err != nil {
+			return nil, err
+		}
+		buf := make([]byte, binary.BigEndian.Uint16(size[:]))
+		if _, err := io.ReadFull(conn, buf); err != nil {
+			return nil, err
+		}
+		return unpackReply(buf, id)
+	}
+
+	if _, err := conn.Write(packet); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 4096)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		if reply, err := unpackReply(buf[:n], id); err == nil {
+			return reply, nil
+		}
+	}
+}
+
+// unpackReply parses a DNS reply and checks that it answers the query id
+func unpackReply(data []byte, id uint16) (*dnsmessage.Message, error) {
+	var reply dnsmessage.Message
+	if err := reply.Unpack(data); err != nil {
+		return nil, err
+	}
+	if !reply.Response || reply.ID != id {
+		return nil, fmt.Errorf("unexpected DNS message")
+	}
+	return &reply, nil
+}
@@ -0,0 +1,229 @@
+package scanner
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"reflect"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// dnsServer is a local DNS server stand-in answering from a fixed zone
+type dnsServer struct {
+	addr    string
+	queries atomic.Int32
+}
+
+// testZone holds the records of example.test; other names do not exist
+var testZone = map[string][]DNSRecord{
+	"www.example.test":  {{RecordA, "192.0.2.10"}, {RecordAAAA, "2001:db8::10"}},
+	"mail.example.test": {{RecordCNAME, "mx.example.test"}},
+	"mx.example.test":   {{RecordA, "192.0.2.25"}},
+}
+
+// newDNSServer serves zone over UDP. rcode, when not RCodeSuccess, is
+// returned for every query instead; silent servers never answer.
+func newDNSServer(t *testing.T, zone map[string][]DNSRecord, rcode dnsmessage.RCode, silent bool) *dnsServer {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	srv := &dnsServer{addr: conn.LocalAddr().String()}
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, from, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			count := srv.queries.Add(1)
+			var query dnsmessage.Message
+			if silent || query.Unpack(buf[:n]) != nil || len(query.Questions) != 1 {
+				continue
+			}
+			reply := answer(query, zone, rcode, int(count))
+			if packet, err := reply.Pack(); err == nil {
+				conn.WriteTo(packet, from)
+			}
+		}
+	}()
+	return srv
+}
+
+// lookupZone returns the records of name in zone, or those of the closest
+// wildcard. A wildcard answers with one of its records in turn (query n),
+// like round-robin DNS.
+func lookupZone(zone map[string][]DNSRecord, name string, n int) []DNSRecord {
+	if records, ok := zone[name]; ok {
+		return records
+	}
+	for parent := parentZone(name); parent != ""; parent = parentZone(parent) {
+		if records, ok := zone["*."+parent]; ok {
+			i := n % len(records)
+			return records[i : i+1]
+		}
+	}
+	return nil
+}
+
+// answer builds the reply to query n from zone, following CNAMEs like a
+// recursive resolver
+func answer(query dnsmessage.Message, zone map[string][]DNSRecord, rcode dnsmessage.RCode, n int) dnsmessage.Message {
+	q := query.Questions[0]
+	reply := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: query.ID, Response: true, RecursionAvailable: true, RCode: rcode},
+		Questions: query.Questions,
+	}
+	if rcode != dnsmessage.RCodeSuccess {
+		return reply
+	}
+
+	name := strings.TrimSuffix(q.Name.String(), ".")
+	records := lookupZone(zone, name, n)
+	if records == nil {
+		reply.RCode = dnsmessage.RCodeNameError
+		return reply
+	}
+	for len(records) > 0 {
+		next := ""
+		for _, rec := range records {
+			h := dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName(name + "."), Class: dnsmessage.ClassINET, TTL: 60}
+			switch {
+			case rec.Type == RecordCNAME:
+				h.Type = dnsmessage.TypeCNAME
+				reply.Answers = append(reply.Answers, dnsmessage.Resource{Header: h,
+					Body: &dnsmessage.CNAMEResource{CNAME: dnsmessage.MustNewName(rec.Value + ".")}})
+				next = rec.Value
+			case rec.Type == RecordA && q.Type == dnsmessage.TypeA:
+				h.Type = dnsmessage.TypeA
+				reply.Answers = append(reply.Answers, dnsmessage.Resource{Header: h,
+					Body: &dnsmessage.AResource{A: netip.MustParseAddr(rec.Value).As4()}})
+			case rec.Type == RecordAAAA && q.Type == dnsmessage.TypeAAAA:
+				h.Type = dnsmessage.TypeAAAA
+				reply.Answers = append(reply.Answers, dnsmessage.Resource{Header: h,
+					Body: &dnsmessage.AAAAResource{AAAA: netip.MustParseAddr(rec.Value).As16()}})
+			}
+		}
+		if next == "" || q.Type == dnsmessage.TypeCNAME {
+			break
+		}
+		name, records = next, lookupZone(zone, next, n)
+	}
+	return reply
+}
+
+func TestResolver(t *testing.T) {
+	srv := newDNSServer(t, testZone, dnsmessage.RCodeSuccess, false)
+	r, err := newResolver(&Config{Resolvers: srv.addr, DNSTypes: "a,AAAA,cname"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		want []DNSRecord
+	}{
+		{"www.example.test", []DNSRecord{{RecordA, "192.0.2.10"}, {RecordAAAA, "2001:db8::10"}}},
+		{"mail.example.test", []DNSRecord{{RecordA, "192.0.2.25"}, {RecordCNAME, "mx.example.test"}}},
+		{"nope.example.test", nil},
+	}
+	for _, tt := range tests {
+		got, _, err := r.resolve(context.Background(), tt.name)
+		if err != nil {
+			t.Errorf("resolve(%s): %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("resolve(%s) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	for _, cfg := range []*Config{{Resolvers: "dns.example"}, {Resolvers: srv.addr, DNSTypes: "MX"}, {Resolvers: " , "}} {
+		if _, err := newResolver(cfg); err == nil {
+			t.Errorf("newResolver(%+v): expected an error", *cfg)
+		}
+	}
+}
+
+func TestResolver_Failures(t *testing.T) {
+	good := newDNSServer(t, testZone, dnsmessage.RCodeSuccess, false)
+	broken := newDNSServer(t, testZone, dnsmessage.RCodeServerFailure, false)
+	silent := newDNSServer(t, testZone, dnsmessage.RCodeSuccess, true)
+
+	// Failed queries move on to the next resolver, and resolvers that keep
+	// failing are skipped
+	r, err := newResolver(&Config{Resolvers: broken.addr + "," + silent.addr + "," + good.addr, Retries: 2, DNSTimeout: 50})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 30; i++ {
+		records, _, err := r.resolve(context.Background(), "www.example.test")
+		if err != nil || len(records) != 1 {
+			t.Fatalf("lookup %d: records %v, error %v", i, records, err)
+		}
+	}
+	for _, srv := range []*dnsServer{broken, silent} {
+		if n := srv.queries.Load(); n > maxResolverFailures {
+			t.Errorf("failing resolver got %d queries, want at most %d", n, maxResolverFailures)
+		}
+	}
+
+	// Every resolver fails: the error says why
+	r, err = newResolver(&Config{Resolvers: broken.addr, Retries: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, attempts, err := r.resolve(context.Background(), "www.example.test")
+	if err == nil || classifyError(err) != ErrorDNS || attempts != 2 {
+		t.Errorf("error = %v (%d attempts), want a dns failure after 2 attempts", err, attempts)
+	}
+	r, _ = newResolver(&Config{Resolvers: silent.addr, DNSTimeout: 50})
+	if _, _, err := r.resolve(context.Background(), "www.example.test"); classifyError(err) != ErrorTimeout {
+		t.Errorf("error = %v, want a timeout", err)
+	}
+}
+
+func TestScanner_DNSOnly(t *testing.T) {
+	srv := newDNSServer(t, testZone, dnsmessage.RCodeSuccess, false)
+	cfg := testConfig("example.test", writeWordlist(t, "www", "mail", "nope", "dev"))
+	cfg.DNSOnly = true
+	cfg.DNSThreads = 2
+	cfg.Resolvers = srv.addr
+	cfg.DNSTypes = "A,CNAME"
+
+	s := New(cfg)
+	var got []string
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for r := range s.Results() {
+			got = append(got, r.String())
+		}
+	}()
+	if err := s.Run(context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	<-done
+	sort.Strings(got)
+	want := []string{
+		"[DNS] mail.example.test A 192.0.2.25, CNAME mx.example.test",
+		"[DNS] www.example.test A 192.0.2.10",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("results = %q, want %q", got, want)
+	}
+	if stats := s.Stats(); stats.ProcessedCount != 4 || stats.FailedCount != 0 {
+		t.Errorf("processed %d, failed %d; want 4 and 0", stats.ProcessedCount, stats.FailedCount)
+	}
+
+	cfg.Subdomain = true
+	if err := ValidateDNS(cfg); err == nil {
+		t.Error("expected an error with subdomain mode")
+	}
+}
@@ -0,0 +1,179 @@
+package scanner
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// Defaults used when Config.RetryBackoff and Config.MaxBackoff are not set
+const (
+	defaultRetryBackoff = 100 * time.Millisecond
+	defaultMaxBackoff   = 5 * time.Second
+)
+
+// Failure is a request that still failed after every retry: a transport
+// error, or a retryable status when Status is set
+type Failure struct {
+	Path     string            `json:"path"`
+	Target   string            `json:"target,omitempty"`
+	Input    map[string]string `json:"input,omitempty"`
+	VHost    string            `json:"vhost,omitempty"`
+	Attempts int               `json:"attempts"`
+	Status   int               `json:"status,omitempty"`
+	Category string            `json:"category"`
+	Error    string            `json:"error"`
+}
+
+// String renders a failure as a single line
+func (f Failure) String() string {
+	name := f.Path
+	if f.VHost != "" {
+		name = f.VHost + " via " + f.Path
+	}
+	return fmt.Sprintf("[ERR] %s (%s, %d attempts)", name, f.Error, f.Attempts)
+}
+
+// retryPolicy decides when and how long to wait before a request is sent
+// again
+type retryPolicy struct {
+	retries  int
+	base     time.Duration
+	max      time.Duration
+	delay    time.Duration
+	statuses *numberSet
+	deadline time.Duration
+}
+
+// ValidateRetryPolicy checks the retry settings of cfg, so that invalid input
+// is reported before a scan starts
+func ValidateRetryPolicy(cfg *Config) error {
+	_, err := parseRetryPolicy(cfg)
+	return err
+}
+
+func parseRetryPolicy(cfg *Config) (*retryPolicy, error) {
+	p := &retryPolicy{
+		retries:  max(cfg.Retries, 0),
+		base:     time.Duration(cfg.RetryBackoff) * time.Millisecond,
+		max:      time.Duration(cfg.MaxBackoff) * time.Millisecond,
+		delay:    time.Duration(cfg.Delay) * time.Millisecond,
+		deadline: time.Duration(cfg.RequestDeadline) * time.Second,
+	}
+	if p.base <= 0 {
+		p.base = defaultRetryBackoff
+	}
+	if p.max <= 0 {
+		p.max = defaultMaxBackoff
+	}
+	if p.max < p.base {
+		return nil, fmt.Errorf("max backoff %v is shorter than the retry backoff %v", p.max, p.base)
+	}
+
+	statuses, err := parseNumberSet(cfg.RetryStatus, true, parseNumber)
+	if err != nil {
+		return nil, fmt.Errorf("invalid retry statuses %q: %w", cfg.RetryStatus, err)
+	}
+	p.statuses = statuses
+	return p, nil
+}
+
+// backoff returns the wait before retry number attempt (1 for the first
+// retry): the base backoff doubled on every attempt, capped at the maximum,
+// with equal jitter so that concurrent workers do not retry in lockstep. It
+// is never shorter than Config.Delay.
+func (p *retryPolicy) backoff(attempt int) time.Duration {
+	// Double without shifting past the maximum, which could overflow
+	d := min(p.base, p.max)
+	for i := 1; i < attempt && d < p.max; i++ {
+		if d > p.max/2 {
+			d = p.max
+		} else {
+			d *= 2
+		}
+	}
+	d = max(d, 1)
+	d = d/2 + rand.N(d/2+1)
+	return max(d, p.delay)
+}
+
+// send sends req until it succeeds or the retry policy gives up. Transport
+// errors and retryable statuses are retried after a backoff (or the
+// Retry-After of the response, up to the maximum backoff) within the request
+// deadline. It returns the duration of the last attempt and, when every
+// attempt failed, the failure with its Path and Input left empty. It returns
+// false if the scan was stopped.
+func (h *host) send(req *fasthttp.Request, resp *fasthttp.Response) (time.Duration, *Failure, bool) {
+	p := h.s.retry
+	var deadline time.Time
+	if p.deadline > 0 {
+		deadline = time.Now().Add(p.deadline)
+	}
+
+	for attempt := 1; ; attempt++ {
+		sent := time.Now()
+		err := h.s.transport.Do(req, resp, deadline)
+		duration := time.Since(sent)
+		h.observe(resp, err)
+
+		failure := &Failure{Attempts: attempt}
+		wait := p.backoff(attempt)
+		switch {
+		case err != nil:
+			failure.Category = classifyError(err)
+			failure.Error = err.Error()
+		case p.statuses.has(resp.StatusCode()):
+			failure.Status = resp.StatusCode()
+			failure.Category = ErrorStatus
+			failure.Error = fmt.Sprintf("status %d", failure.Status)
+			if after := parseRetryAfter(string(resp.Header.Peek("Retry-After")), time.Now()); after > wait {
+				wait = min(after, p.max)
+			}
+		default:
+			return duration, nil, true
+		}
+
+		if attempt > p.retries {
+			return duration, failure, true
+		}
+		if !deadline.IsZero() && time.Now().Add(wait).After(deadline) {
+			failure.Error += " (request deadline exceeded)"
+			return duration, failure, true
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-h.s.ctx.Done():
+			timer.Stop()
+			return duration, nil, false
+		}
+		if !h.s.gate.wait(h.s.ctx) || !h.pace() {
+			return duration, nil, false
+		}
+	}
+}
+
+// recordFailure stores a request that failed on h after every retry
+func (s *Scanner) recordFailure(h *host, failure Failure) {
+	failure.Target = h.url
+
+	s.mu.Lock()
+	s.failures = append(s.failures, failure)
+	count := len(s.failures)
+	s.mu.Unlock()
+
+	s.statsMu.Lock()
+	s.stats.FailedCount = count
+	s.stats.Errors.add(failure.Category)
+	s.statsMu.Unlock()
+}
+
+// Failures returns a copy of the requests that failed after every retry
+func (s *Scanner) Failures() []Failure {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Failure{}, s.failures...)
+}
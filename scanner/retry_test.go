@@ -0,0 +1,54 @@
+package scanner
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRetryBackoff(t *testing.T) {
+	p, err := parseRetryPolicy(&Config{RetryBackoff: 100, MaxBackoff: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		attempt int
+		lo, hi  time.Duration
+	}{
+		{1, 50 * time.Millisecond, 100 * time.Millisecond},
+		{2, 100 * time.Millisecond, 200 * time.Millisecond},
+		{4, 400 * time.Millisecond, 800 * time.Millisecond},
+		{5, 500 * time.Millisecond, time.Second},
+		{64, 500 * time.Millisecond, time.Second},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 20; i++ {
+			if d := p.backoff(tt.attempt); d < tt.lo || d > tt.hi {
+				t.Fatalf("backoff(%d) = %v, want within [%v, %v]", tt.attempt, d, tt.lo, tt.hi)
+			}
+		}
+	}
+
+	// A base close to the maximum must not overflow on late attempts
+	large, err := parseRetryPolicy(&Config{RetryBackoff: 1 << 33, MaxBackoff: 1<<33 + 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for attempt := 1; attempt <= 100; attempt++ {
+		if d := large.backoff(attempt); d < large.max/2 || d > large.max {
+			t.Fatalf("backoff(%d) = %v, want within [%v, %v]", attempt, d, large.max/2, large.max)
+		}
+	}
+
+	// --delay is a floor for every retry
+	p.delay = 2 * time.Second
+	if d := p.backoff(1); d != 2*time.Second {
+		t.Errorf("backoff with delay = %v, want 2s", d)
+	}
+
+	if _, err := parseRetryPolicy(&Config{RetryStatus: "5zz"}); err == nil {
+		t.Error("expected an error for an invalid retry status")
+	}
+	if _, err := parseRetryPolicy(&Config{RetryBackoff: 500, MaxBackoff: 100}); err == nil {
+		t.Error("expected an error for a max backoff below the backoff")
+	}
+}
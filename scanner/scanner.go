@@ -0,0 +1,475 @@
+// Package scanner implements the Preekeeper scanning engine: a worker pool that
+// brute-forces paths or subdomains with fasthttp and streams matching results.
+//
+// The TUI is one consumer of this package; other tools can embed it directly:
+//
+//	s := scanner.New(cfg)
+//	go func() {
+//		for r := range s.Results() {
+//			fmt.Println(r)
+//		}
+//	}()
+//	if err := s.Run(ctx); err != nil {
+//		log.Fatal(err)
+//	}
+package scanner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Result is a response that passed the status and filter checks
+type Result struct {
+	Path   string `json:"path"`
+	Status int    `json:"status"`
+	Size   int    `json:"size"`
+	Lines  int    `json:"lines"`
+	Words  int    `json:"words"`
+	// Response time of the request, written as duration_ms in JSON
+	Duration time.Duration `json:"-"`
+	// Location header of a 3xx response, resolved against Path
+	Location string `json:"location,omitempty"`
+	// With Config.FollowRedirects: every URL visited after Path and the
+	// status and size of the last response
+	RedirectChain []string `json:"redirect_chain,omitempty"`
+	FinalStatus   int      `json:"final_status,omitempty"`
+	FinalSize     int      `json:"final_size,omitempty"`
+	// Base URL of the target the result belongs to
+	Target string `json:"target,omitempty"`
+	// Keyword values used for the request (keyword wordlists only)
+	Input map[string]string `json:"input,omitempty"`
+	// Host header sent to Path (vhost mode only)
+	VHost string `json:"vhost,omitempty"`
+	// Records of the name in Path (DNS-only mode, where Status is 0)
+	Records []DNSRecord `json:"records,omitempty"`
+}
+
+// String formats a result as a single line, as shown in the TUI and in headless output
+func (r Result) String() string {
+	if len(r.Records) > 0 {
+		records := make([]string, len(r.Records))
+		for i, rec := range r.Records {
+			records[i] = rec.String()
+		}
+		return "[DNS] " + r.Path + " " + strings.Join(records, ", ")
+	}
+	name := r.Path
+	if r.VHost != "" {
+		name = r.VHost + " via " + r.Path
+	}
+	line := fmt.Sprintf("[%d] %s (Size: %d, Lines: %d, Words: %d, Time: %dms)",
+		r.Status, name, r.Size, r.Lines, r.Words, r.Duration.Milliseconds())
+	if n := len(r.RedirectChain); n > 0 {
+		hops := "hops"
+		if n == 1 {
+			hops = "hop"
+		}
+		line += fmt.Sprintf(" → %s [%d, Size: %d, %d %s]", r.RedirectChain[n-1], r.FinalStatus, r.FinalSize, n, hops)
+	} else if r.Location != "" {
+		line += " → " + r.Location
+	}
+	if len(r.Input) > 0 {
+		line += " [" + r.inputString() + "]"
+	}
+	return line
+}
+
+// resultJSON is the JSON form of a Result: the duration in milliseconds, the
+// unit of --mt/--ft and of the TUI
+type resultJSON struct {
+	plainResult
+	DurationMS int64 `json:"duration_ms"`
+}
+
+// plainResult has the fields of Result without its JSON methods
+type plainResult Result
+
+// MarshalJSON writes the duration as duration_ms
+func (r Result) MarshalJSON() ([]byte, error) {
+	return json.Marshal(resultJSON{plainResult(r), r.Duration.Milliseconds()})
+}
+
+// UnmarshalJSON reads the form written by MarshalJSON
+func (r *Result) UnmarshalJSON(data []byte) error {
+	var v resultJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*r = Result(v.plainResult)
+	r.Duration = time.Duration(v.DurationMS) * time.Millisecond
+	return nil
+}
+
+// Key identifies the request behind a result: its URL, virtual host and
+// keyword values
+func (r Result) Key() string {
+	if r.VHost != "" {
+		return r.Path + " Host=" + r.VHost + " " + r.inputString()
+	}
+	return r.Path + " " + r.inputString()
+}
+
+// inputString renders the keyword values as sorted KEY=value pairs
+func (r Result) inputString() string {
+	pairs := make([]string, 0, len(r.Input))
+	for k, v := range r.Input {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, " ")
+}
+
+// Stats is a snapshot of the scan progress
+type Stats struct {
+	ProcessedCount  int
+	FoundCount      int
+	RecursionCount  int
+	RecursionActive bool
+	CurrentPath     string
+	RPS             float64
+	Elapsed         string
+	// Directories with a learned soft-404 baseline (auto-calibration)
+	Calibrated int
+	// Requests that failed after every retry, in total and by category
+	FailedCount int
+	Errors      ErrorCounts
+	// Why the circuit breaker paused the scan; empty when it is not tripped
+	Breaker string
+	// Results the replay proxy could not resend, and the last error
+	ReplayFailed int
+	ReplayError  string
+	// Progress of every target, in Config.Targets order
+	Hosts []HostStats
+}
+
+// HostStats is the progress of a single target
+type HostStats struct {
+	URL       string
+	Processed int
+	Found     int
+	Done      bool
+	// Effective request rate set by the adaptive throttle (0 for unlimited)
+	// and whether the target is currently slowed down
+	Rate      float64
+	Throttled bool
+}
+
+// Job is a single candidate handed from the producer to the workers
+type Job struct {
+	URL   string
+	Depth int
+	// For subdomain fuzzing we may use Label and Path; vhost mode uses Label
+	Label string
+	Path  string
+	// Values of the keyword wordlists, in Config.Wordlists order
+	Values []string
+
+	seq uint64 // position in the job stream, assigned by enqueue
+	dir string // recursion directory of the job, "" for the base URL
+}
+
+// RecursionTarget is a discovered directory whose contents should be scanned
+type RecursionTarget struct {
+	URL   string `json:"url"`
+	Depth int    `json:"depth"`
+}
+
+// Cursor is the producer's position in the job stream of the current target.
+// It always points to the next job to enqueue.
+type Cursor struct {
+	Word int `json:"word"` // index in the wordlist (line index in pitchfork mode)
+	Ext  int `json:"ext"`  // 0 for the bare word, n for the n-th extension
+	Path int `json:"path"` // path index for --subdomain-paths
+	// Index in every keyword wordlist (clusterbomb mode)
+	Words []int `json:"words,omitempty"`
+}
+
+// jobPosition records where an in-flight job came from, so a checkpoint can
+// restart from the oldest job that has not finished yet.
+type jobPosition struct {
+	target *RecursionTarget // nil for the base URL
+	cursor Cursor
+}
+
+// progressInterval is how often a Stats snapshot is published on Progress()
+const progressInterval = 200 * time.Millisecond
+
+// Scanner runs a scan described by a Config. A Scanner runs once: create a new
+// one with New for every scan.
+type Scanner struct {
+	config *Config
+
+	ctx       context.Context
+	cancel    context.CancelFunc
+	startTime time.Time
+
+	// One producer and worker pool per target
+	hosts []*host
+
+	// Output streams, closed when Run returns. progressMu guards the close
+	// of progress against Pause and Resume called after Run.
+	results        chan Result
+	progress       chan Stats
+	progressMu     sync.Mutex
+	progressClosed bool
+
+	// Results found so far and progress counters
+	mu       sync.Mutex
+	found    []Result
+	failures []Failure
+	statsMu  sync.Mutex
+	stats    Stats
+
+	// Pause barrier shared by every target
+	gate gate
+
+	// When and how failed requests are sent again, and the error-rate
+	// circuit breaker (nil when disabled)
+	retry   *retryPolicy
+	breaker *breaker
+
+	// Sends every request; built from the configuration unless SetTransport
+	// replaced it. replay resends matching requests through
+	// Config.ReplayProxy (nil when not set).
+	transport Transport
+	replay    Transport
+
+	// Resolves the labels of a DNS-only scan and the wildcard probes (nil
+	// when no resolver is configured outside DNS-only mode)
+	resolver *resolver
+
+	// First error that stopped a producer, such as a wordlist read error
+	errMu sync.Mutex
+	err   error
+
+	// Wildcard DNS detection, per zone
+	wildcardMu sync.Mutex
+	wildcards  map[string]*wildcardZone
+
+	// Checkpointing: keys of results restored from a state file (never emitted
+	// twice) and active scan time accumulated before the restore.
+	known        map[string]bool
+	priorElapsed time.Duration
+}
+
+// New creates a scanner for cfg. Nothing is sent until Run is called.
+func New(cfg *Config) *Scanner {
+	s := &Scanner{
+		config:    cfg,
+		results:   make(chan Result, 64),
+		progress:  make(chan Stats, 1),
+		wildcards: make(map[string]*wildcardZone),
+		known:     make(map[string]bool),
+		breaker:   newBreaker(cfg),
+	}
+	for i, url := range targetURLs(cfg) {
+		s.hosts = append(s.hosts, newHost(s, i, url))
+		s.stats.Hosts = append(s.stats.Hosts, HostStats{URL: url})
+	}
+	return s
+}
+
+// Results streams every matching result. The channel must be drained by the
+// caller while Run is executing; it is closed when Run returns.
+func (s *Scanner) Results() <-chan Result {
+	return s.results
+}
+
+// Progress publishes Stats snapshots while the scan runs. Snapshots are
+// dropped when the caller does not keep up, so reading it is optional. The
+// channel is closed when Run returns.
+func (s *Scanner) Progress() <-chan Stats {
+	return s.progress
+}
+
+// Stats returns the current progress snapshot
+func (s *Scanner) Stats() Stats {
+	s.statsMu.Lock()
+	defer s.statsMu.Unlock()
+	stats := s.stats
+	stats.Hosts = append([]HostStats{}, s.stats.Hosts...)
+	return stats
+}
+
+// Cursors returns the producer's position in the job stream of every target
+func (s *Scanner) Cursors() []Cursor {
+	cursors := make([]Cursor, len(s.hosts))
+	for i, h := range s.hosts {
+		h.mu.Lock()
+		cursors[i] = h.cursor
+		h.mu.Unlock()
+	}
+	return cursors
+}
+
+// Found returns a copy of all results found so far
+func (s *Scanner) Found() []Result {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Result{}, s.found...)
+}
+
+// Run streams the wordlist and scans every target until all jobs (including
+// recursion) are done or ctx is cancelled. It returns ctx.Err() when cancelled
+// and the first wordlist read error, if any, once the scan is over.
+func (s *Scanner) Run(ctx context.Context) error {
+	defer close(s.results)
+	defer s.closeProgress()
+
+	if err := validateKeywords(s.config); err != nil {
+		return err
+	}
+	if err := ValidateVHost(s.config); err != nil {
+		return err
+	}
+	if err := ValidateDNS(s.config); err != nil {
+		return err
+	}
+	if err := s.checkWordlists(); err != nil {
+		return err
+	}
+
+	// The circuit breaker cancels the internal context to abort the scan
+	s.ctx, s.cancel = context.WithCancel(ctx)
+	defer s.cancel()
+	s.startTime = time.Now()
+
+	f, err := parseFilters(s.config)
+	if err != nil {
+		return err
+	}
+	if s.retry, err = parseRetryPolicy(s.config); err != nil {
+		return err
+	}
+	if s.transport == nil {
+		if s.transport, err = NewTransport(s.config); err != nil {
+			return err
+		}
+	}
+	if s.replay, err = newReplayTransport(s.config); err != nil {
+		return err
+	}
+	if s.config.DNSOnly || s.config.Resolvers != "" {
+		if s.resolver, err = newResolver(s.config); err != nil {
+			return err
+		}
+	}
+
+	// Scan every target concurrently, each with its own workers
+	var hosts sync.WaitGroup
+	hosts.Add(len(s.hosts))
+	for _, h := range s.hosts {
+		go func(h *host) {
+			defer hosts.Done()
+			h.run(f)
+		}(h)
+	}
+
+	// Publish progress and write checkpoints until the workers are done
+	done := make(chan struct{})
+	reported := make(chan struct{})
+	go func() {
+		s.reportProgress(done)
+		close(reported)
+	}()
+	checkpointed := make(chan error, 1)
+	go func() { checkpointed <- s.checkpointLoop(done) }()
+
+	// Wait for every target to finish
+	hosts.Wait()
+	s.clearBreaker()
+	close(done)
+	<-reported
+	s.publishProgress()
+
+	if err := <-checkpointed; err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.errMu.Lock()
+	defer s.errMu.Unlock()
+	return s.err
+}
+
+// fail records an error that stopped part of the scan; Run returns the first one
+func (s *Scanner) fail(err error) {
+	s.errMu.Lock()
+	defer s.errMu.Unlock()
+	if s.err == nil {
+		s.err = err
+	}
+}
+
+// reportProgress publishes a Stats snapshot every progressInterval until done is closed
+func (s *Scanner) reportProgress(done chan struct{}) {
+	ticker := time.NewTicker(progressInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.publishProgress()
+		case <-done:
+			return
+		}
+	}
+}
+
+// publishProgress replaces any unread snapshot with the current one without
+// blocking. Nothing is published once Run has returned.
+func (s *Scanner) publishProgress() {
+	stats := s.Stats()
+	s.progressMu.Lock()
+	defer s.progressMu.Unlock()
+	if s.progressClosed {
+		return
+	}
+	select {
+	case <-s.progress:
+	default:
+	}
+	select {
+	case s.progress <- stats:
+	default:
+	}
+}
+
+// closeProgress closes the progress stream when Run returns
+func (s *Scanner) closeProgress() {
+	s.progressMu.Lock()
+	s.progressClosed = true
+	close(s.progress)
+	s.progressMu.Unlock()
+}
+
+// emit records a result found on h and streams it to the caller
+func (s *Scanner) emit(h *host, result Result) {
+	result.Target = h.url
+
+	s.mu.Lock()
+	if s.known[result.Key()] {
+		s.mu.Unlock()
+		return
+	}
+	s.known[result.Key()] = true
+	s.found = append(s.found, result)
+	count := len(s.found)
+	s.mu.Unlock()
+
+	s.statsMu.Lock()
+	s.stats.FoundCount = count
+	s.stats.Hosts[h.index].Found++
+	s.statsMu.Unlock()
+
+	select {
+	case s.results <- result:
+	case <-s.ctx.Done():
+	}
+}
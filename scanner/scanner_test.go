@@ -0,0 +1,930 @@
+package scanner
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestSite serves /admin/ (with /admin/secret/ and /admin/secret/key.txt) and /index.html
+func newTestSite(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/index.html", "/admin/secret/key.txt":
+			w.Write([]byte("found\n"))
+		case "/admin", "/admin/secret":
+			http.Redirect(w, r, r.URL.Path+"/", http.StatusMovedPermanently)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func writeWordlist(t *testing.T, words ...string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "words.txt")
+	if err := os.WriteFile(path, []byte(strings.Join(words, "\n")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func testConfig(url, wordlist string) *Config {
+	return &Config{
+		URL:         url,
+		Wordlist:    wordlist,
+		Threads:     4,
+		Method:      "GET",
+		StatusCodes: "200,301",
+		Timeout:     5,
+		MaxDepth:    2,
+	}
+}
+
+// runScan runs a scanner to completion and returns the sorted result paths
+func runScan(t *testing.T, cfg *Config) []string {
+	t.Helper()
+	s := New(cfg)
+	var paths []string
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for r := range s.Results() {
+			paths = append(paths, strings.TrimPrefix(r.Path, cfg.URL))
+		}
+	}()
+	if err := s.Run(context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	<-done
+	sort.Strings(paths)
+	return paths
+}
+
+func TestScanner_Run(t *testing.T) {
+	srv := newTestSite(t)
+	cfg := testConfig(srv.URL, writeWordlist(t, "index.html", "admin", "missing"))
+
+	got := runScan(t, cfg)
+	want := []string{"/admin", "/index.html"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("results = %v, want %v", got, want)
+	}
+}
+
+func TestScanner_Recursion(t *testing.T) {
+	srv := newTestSite(t)
+	cfg := testConfig(srv.URL, writeWordlist(t, "admin", "secret", "key.txt"))
+	cfg.Recursion = true
+
+	got := runScan(t, cfg)
+	want := []string{"/admin", "/admin/secret", "/admin/secret/key.txt"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("results = %v, want %v", got, want)
+	}
+
+	cfg.MaxDepth = 1
+	got = runScan(t, cfg)
+	want = []string{"/admin", "/admin/secret"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("results with depth 1 = %v, want %v", got, want)
+	}
+}
+
+func TestScanner_MissingWordlist(t *testing.T) {
+	s := New(testConfig("http://127.0.0.1", filepath.Join(t.TempDir(), "none.txt")))
+	if err := s.Run(context.Background()); err == nil {
+		t.Fatal("expected an error for a missing wordlist")
+	}
+}
+
+func TestDirectoryURL(t *testing.T) {
+	cases := []struct {
+		url      string
+		status   int
+		location string
+		want     string
+		ok       bool
+	}{
+		{"http://example.com/admin", 301, "/admin/", "http://example.com/admin/", true},
+		{"http://example.com/admin", 302, "http://example.com/admin/", "http://example.com/admin/", true},
+		{"http://example.com/admin", 301, "/login", "", false},
+		{"http://example.com/admin", 301, "http://other.com/admin/", "", false},
+		{"http://example.com/admin/", 403, "", "http://example.com/admin/", true},
+		{"http://example.com/admin", 403, "", "", false},
+		{"http://example.com/", 200, "", "", false},
+		{"http://example.com/admin/", 404, "", "", false},
+	}
+
+	for _, c := range cases {
+		got, ok := directoryURL(c.url, c.status, c.location)
+		if got != c.want || ok != c.ok {
+			t.Errorf("directoryURL(%q, %d, %q) = %q, %v; want %q, %v", c.url, c.status, c.location, got, ok, c.want, c.ok)
+		}
+	}
+}
+
+func TestScanner_PauseResume(t *testing.T) {
+	var mu sync.Mutex
+	hits := make(map[string]int)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits[r.URL.Path]++
+		mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	words := make([]string, 60)
+	for i := range words {
+		words[i] = fmt.Sprintf("w%d", i)
+	}
+	cfg := testConfig(srv.URL, writeWordlist(t, words...))
+	cfg.Threads = 2
+	cfg.Extensions = ".php"
+
+	s := New(cfg)
+	go func() {
+		for range s.Results() {
+		}
+	}()
+	done := make(chan error)
+	go func() { done <- s.Run(context.Background()) }()
+
+	time.Sleep(50 * time.Millisecond)
+	s.Pause()
+	time.Sleep(30 * time.Millisecond) // let in-flight requests finish
+	paused := s.Stats().ProcessedCount
+	cursor := s.Cursors()
+	time.Sleep(100 * time.Millisecond)
+	if got := s.Stats().ProcessedCount; got != paused {
+		t.Fatalf("processed count moved while paused: %d -> %d", paused, got)
+	}
+	if got := s.Cursors(); !reflect.DeepEqual(got, cursor) {
+		t.Fatalf("cursor moved while paused: %+v -> %+v", cursor, got)
+	}
+	s.Resume()
+
+	if err := <-done; err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if got := s.Stats().ProcessedCount; got != len(words)*2 {
+		t.Fatalf("processed = %d, want %d", got, len(words)*2)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	for path, n := range hits {
+		if n != 1 {
+			t.Errorf("%s requested %d times", path, n)
+		}
+	}
+	if len(hits) != len(words)*2 {
+		t.Errorf("requested %d paths, want %d", len(hits), len(words)*2)
+	}
+}
+
+func TestScanner_PauseAfterRun(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	s := New(testConfig(srv.URL, writeWordlist(t, "a", "b")))
+	go func() {
+		for range s.Results() {
+		}
+	}()
+	if err := s.Run(context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	// The TUI may still pause while it writes the output of a finished scan
+	s.Pause()
+	if !s.Paused() {
+		t.Error("not paused")
+	}
+	s.Resume()
+	for range s.Progress() {
+	}
+}
+
+func TestScanner_CheckpointRestore(t *testing.T) {
+	var mu sync.Mutex
+	hits := make(map[string]int)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits[r.URL.Path]++
+		mu.Unlock()
+		time.Sleep(2 * time.Millisecond)
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	words := make([]string, 80)
+	for i := range words {
+		words[i] = fmt.Sprintf("w%d", i)
+	}
+	cfg := testConfig(srv.URL, writeWordlist(t, words...))
+	cfg.Threads = 3
+	cfg.StateFile = filepath.Join(t.TempDir(), "scan.state")
+
+	// Interrupt the first run part-way through
+	ctx, cancel := context.WithCancel(context.Background())
+	first := New(cfg)
+	go func() {
+		for range first.Results() {
+		}
+	}()
+	go func() {
+		time.Sleep(40 * time.Millisecond)
+		cancel()
+	}()
+	if err := first.Run(ctx); err != context.Canceled {
+		t.Fatalf("first Run = %v, want context.Canceled", err)
+	}
+
+	state, err := LoadState(cfg.StateFile)
+	if err != nil {
+		t.Fatalf("LoadState: %v", err)
+	}
+	if len(state.Results) == 0 || len(state.Results) == len(words) {
+		t.Fatalf("checkpoint has %d results, want a partial scan", len(state.Results))
+	}
+
+	second := New(&state.Config)
+	if err := second.Restore(state); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	var resumed []Result
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for r := range second.Results() {
+			resumed = append(resumed, r)
+		}
+	}()
+	if err := second.Run(context.Background()); err != nil {
+		t.Fatalf("second Run: %v", err)
+	}
+	<-done
+
+	if got := len(state.Results) + len(resumed); got != len(words) {
+		t.Errorf("restored %d + resumed %d results, want %d in total", len(state.Results), len(resumed), len(words))
+	}
+	if got := len(second.Found()); got != len(words) {
+		t.Errorf("Found() = %d results, want %d", got, len(words))
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(hits) != len(words) {
+		t.Errorf("requested %d distinct paths, want %d", len(hits), len(words))
+	}
+}
+
+func TestScanner_KeywordModes(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	users := writeWordlist(t, "alice", "bob")
+	ids := writeWordlist(t, "1", "2", "3")
+
+	cases := []struct {
+		mode string
+		want []string
+	}{
+		{ModeClusterbomb, []string{
+			"/alice?id=1", "/alice?id=2", "/alice?id=3",
+			"/bob?id=1", "/bob?id=2", "/bob?id=3",
+		}},
+		{ModePitchfork, []string{"/alice?id=1", "/bob?id=2"}},
+	}
+
+	for _, c := range cases {
+		cfg := testConfig(srv.URL+"/USER?id=ID", "")
+		cfg.Wordlists = []KeywordWordlist{{Path: users, Keyword: "USER"}, {Path: ids, Keyword: "ID"}}
+		cfg.Mode = c.mode
+
+		s := New(cfg)
+		var got []string
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for r := range s.Results() {
+				got = append(got, strings.TrimPrefix(r.Path, srv.URL))
+				if r.Input["USER"] == "" || r.Input["ID"] == "" {
+					t.Errorf("%s: missing keyword inputs in %+v", c.mode, r)
+				}
+			}
+		}()
+		if err := s.Run(context.Background()); err != nil {
+			t.Fatalf("%s: Run: %v", c.mode, err)
+		}
+		<-done
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: results = %v, want %v", c.mode, got, c.want)
+		}
+	}
+}
+
+func TestScanner_KeywordMissingFromURL(t *testing.T) {
+	cfg := testConfig("http://127.0.0.1/USER", "")
+	cfg.Wordlists = []KeywordWordlist{{Path: writeWordlist(t, "a"), Keyword: "ID"}}
+	if err := New(cfg).Run(context.Background()); err == nil {
+		t.Fatal("expected an error for a keyword missing from the URL")
+	}
+}
+
+func TestScanner_FuzzRequestParts(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		cookie, _ := r.Cookie("role")
+		if r.Header.Get("X-Forwarded-Host") == "internal" && string(body) == `{"user":"internal"}` &&
+			cookie != nil && cookie.Value == "internal" && r.Method == "PUT" {
+			w.Write([]byte("ok"))
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+
+	cfg := testConfig(srv.URL+"/api", writeWordlist(t, "public", "internal", "admin"))
+	cfg.Method = "PUT"
+	cfg.StatusCodes = "200"
+	cfg.Headers = []string{"X-Forwarded-Host: FUZZ"}
+	cfg.Cookies = "role=FUZZ"
+	cfg.Data = `{"user":"FUZZ"}`
+
+	s := New(cfg)
+	var got []Result
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for r := range s.Results() {
+			got = append(got, r)
+		}
+	}()
+	if err := s.Run(context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	<-done
+
+	// The URL has no keyword, so it is requested as is
+	if len(got) != 1 || got[0].Path != srv.URL+"/api" {
+		t.Fatalf("results = %+v, want a single hit on /api", got)
+	}
+	if processed := s.Stats().ProcessedCount; processed != 3 {
+		t.Fatalf("processed = %d, want 3", processed)
+	}
+}
+
+func TestScanner_MultipleTargets(t *testing.T) {
+	fast := newTestSite(t)
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(40 * time.Millisecond)
+		w.Write([]byte("slow\n"))
+	}))
+	defer slow.Close()
+
+	words := []string{"index.html", "a", "b", "c", "d", "e", "f", "g"}
+	cfg := testConfig("", writeWordlist(t, words...))
+	cfg.Targets = []string{fast.URL, slow.URL}
+	cfg.Threads = 1
+
+	s := New(cfg)
+	byTarget := make(map[string]int)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for r := range s.Results() {
+			if !strings.HasPrefix(r.Path, r.Target) {
+				t.Errorf("result %s tagged with target %s", r.Path, r.Target)
+			}
+			byTarget[r.Target]++
+		}
+	}()
+	finished := make(chan error)
+	go func() { finished <- s.Run(context.Background()) }()
+
+	// The fast host must not wait for the slow one
+	deadline := time.After(5 * time.Second)
+	for !s.Stats().Hosts[0].Done {
+		select {
+		case <-deadline:
+			t.Fatal("fast target never finished")
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+	if s.Stats().Hosts[1].Done {
+		t.Error("slow target finished before the fast one")
+	}
+
+	if err := <-finished; err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	<-done
+
+	if byTarget[fast.URL] != 1 || byTarget[slow.URL] != len(words) {
+		t.Errorf("results per target = %v", byTarget)
+	}
+	stats := s.Stats()
+	for i, hs := range stats.Hosts {
+		if hs.URL != cfg.Targets[i] || hs.Processed != len(words) || !hs.Done {
+			t.Errorf("host %d stats = %+v", i, hs)
+		}
+	}
+	if stats.Hosts[0].Found != 1 || stats.Hosts[1].Found != len(words) {
+		t.Errorf("found per host = %d, %d", stats.Hosts[0].Found, stats.Hosts[1].Found)
+	}
+}
+
+func TestScanner_AutoCalibrate(t *testing.T) {
+	// Every unknown path is a 200 page echoing the path; under /admin/ it is
+	// a fixed 403 page instead.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/admin":
+			http.Redirect(w, r, "/admin/", http.StatusMovedPermanently)
+		case r.URL.Path == "/index.html", r.URL.Path == "/admin/index.html":
+			w.Write([]byte("welcome\nto the real page\n"))
+		case strings.HasPrefix(r.URL.Path, "/admin/"):
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte("forbidden"))
+		default:
+			fmt.Fprintf(w, "<html>page %s was not found</html>", r.URL.Path)
+		}
+	}))
+	defer srv.Close()
+
+	cfg := testConfig(srv.URL, writeWordlist(t, "index.html", "a", "missing-page", "admin"))
+	cfg.StatusCodes = "200,301,403"
+	cfg.Recursion = true
+	if got := runScan(t, cfg); len(got) != 8 {
+		t.Fatalf("without calibration got %v", got)
+	}
+
+	cfg.AutoCalibrate = true
+	want := []string{"/admin", "/admin/index.html", "/index.html"}
+	if got := runScan(t, cfg); !reflect.DeepEqual(got, want) {
+		t.Errorf("results = %v, want %v", got, want)
+	}
+}
+
+func TestScanner_ResponseTime(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/report" {
+			time.Sleep(80 * time.Millisecond)
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	cfg := testConfig(srv.URL, writeWordlist(t, "report", "a", "b"))
+	cfg.MatchTime = ">50ms"
+	s := New(cfg)
+	var results []Result
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for r := range s.Results() {
+			results = append(results, r)
+		}
+	}()
+	if err := s.Run(context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	<-done
+	if len(results) != 1 || results[0].Path != srv.URL+"/report" {
+		t.Fatalf("results = %v", results)
+	}
+	if results[0].Duration < 80*time.Millisecond {
+		t.Errorf("duration = %v, want at least 80ms", results[0].Duration)
+	}
+
+	cfg.MatchTime, cfg.FilterTime = "", ">=0.05s"
+	if got := runScan(t, cfg); !reflect.DeepEqual(got, []string{"/a", "/b"}) {
+		t.Errorf("with time filter got %v", got)
+	}
+}
+
+func TestScanner_Redirects(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/old":
+			http.Redirect(w, r, "/mid", http.StatusFound)
+		case "/mid":
+			http.Redirect(w, r, "/new", http.StatusMovedPermanently)
+		case "/new":
+			w.Write([]byte("hello"))
+		case "/loop":
+			http.Redirect(w, r, "/loop", http.StatusFound)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	scan := func(cfg *Config) map[string]Result {
+		s := New(cfg)
+		results := make(map[string]Result)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for r := range s.Results() {
+				results[strings.TrimPrefix(r.Path, srv.URL)] = r
+			}
+		}()
+		if err := s.Run(context.Background()); err != nil {
+			t.Fatalf("Run: %v", err)
+		}
+		<-done
+		return results
+	}
+
+	cfg := testConfig(srv.URL, writeWordlist(t, "old", "loop"))
+	cfg.StatusCodes = "3xx"
+	results := scan(cfg)
+	if got := results["/old"]; got.Location != srv.URL+"/mid" || got.RedirectChain != nil {
+		t.Errorf("without follow: %+v", got)
+	}
+
+	cfg.FollowRedirects = true
+	results = scan(cfg)
+	old := results["/old"]
+	if !reflect.DeepEqual(old.RedirectChain, []string{srv.URL + "/mid", srv.URL + "/new"}) ||
+		old.FinalStatus != 200 || old.FinalSize != 5 || old.Status != 302 {
+		t.Errorf("followed: %+v", old)
+	}
+	if !strings.Contains(old.String(), "→ "+srv.URL+"/new [200") {
+		t.Errorf("String() = %s", old)
+	}
+	if loop := results["/loop"]; len(loop.RedirectChain) != 0 {
+		t.Errorf("loop chain = %v", loop.RedirectChain)
+	}
+}
+
+func TestScanner_AutoThrottle(t *testing.T) {
+	var mu sync.Mutex
+	var limited time.Time
+	var early int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case r.URL.Path == "/slow" && limited.IsZero():
+			limited = time.Now()
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case !limited.IsZero() && time.Since(limited) < 900*time.Millisecond:
+			early++
+		}
+	}))
+	defer srv.Close()
+
+	cfg := testConfig(srv.URL, writeWordlist(t, "slow", "a", "b", "c"))
+	cfg.Threads = 1
+	cfg.AutoThrottle = true
+	s := New(cfg)
+	go func() {
+		for range s.Results() {
+		}
+	}()
+	if err := s.Run(context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if early > 0 {
+		t.Errorf("%d requests sent before Retry-After expired", early)
+	}
+	if h := s.Stats().Hosts[0]; h.Rate == 0 || !h.Throttled {
+		t.Errorf("host stats = %+v, want a reduced rate", h)
+	}
+}
+
+func TestScanner_RetryPolicy(t *testing.T) {
+	var mu sync.Mutex
+	hits := map[string]int{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits[r.URL.Path]++
+		n := hits[r.URL.Path]
+		mu.Unlock()
+		switch {
+		case r.URL.Path == "/flaky" && n <= 2:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case r.URL.Path == "/down":
+			w.WriteHeader(http.StatusBadGateway)
+		case r.URL.Path == "/slow":
+			time.Sleep(1500 * time.Millisecond)
+		}
+	}))
+	defer srv.Close()
+
+	cfg := testConfig(srv.URL, writeWordlist(t, "flaky", "down", "slow"))
+	cfg.Retries = 2
+	cfg.RetryBackoff = 10
+	cfg.MaxBackoff = 50
+	cfg.RetryStatus = "502-504"
+	cfg.RequestDeadline = 1
+	s := New(cfg)
+	var found []string
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for r := range s.Results() {
+			found = append(found, strings.TrimPrefix(r.Path, srv.URL))
+		}
+	}()
+	if err := s.Run(context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	<-done
+
+	if !reflect.DeepEqual(found, []string{"/flaky"}) {
+		t.Errorf("results = %v, want /flaky after two retries", found)
+	}
+	failures := map[string]Failure{}
+	for _, f := range s.Failures() {
+		failures[strings.TrimPrefix(f.Path, srv.URL)] = f
+	}
+	if f := failures["/down"]; f.Attempts != 3 || f.Status != http.StatusBadGateway || f.Target != srv.URL {
+		t.Errorf("/down failure = %+v, want 3 attempts with status 502", f)
+	}
+	if f := failures["/slow"]; f.Attempts != 1 || f.Status != 0 || f.Error == "" {
+		t.Errorf("/slow failure = %+v, want one attempt stopped by the deadline", f)
+	}
+	if got := s.Stats().FailedCount; got != 2 {
+		t.Errorf("FailedCount = %d, want 2", got)
+	}
+}
+
+func TestScanner_CircuitBreaker(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	words := make([]string, 20)
+	for i := range words {
+		words[i] = fmt.Sprintf("w%d", i)
+	}
+	cfg := testConfig(srv.URL, writeWordlist(t, words...))
+	cfg.Threads = 1
+	cfg.Retries = 0
+	cfg.RetryStatus = "5xx"
+	cfg.BreakerThreshold = 50
+	cfg.BreakerWindow = 5
+
+	// Abort: the scan stops with the breaker error before the end of the list
+	cfg.BreakerAction = BreakerAbort
+	aborted := New(cfg)
+	go func() {
+		for range aborted.Results() {
+		}
+	}()
+	err := aborted.Run(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "circuit breaker") {
+		t.Fatalf("Run = %v, want a circuit breaker error", err)
+	}
+	if st := aborted.Stats(); st.Errors.Status != 5 || st.FailedCount != 5 {
+		t.Errorf("errors = %+v (failed %d), want 5 status errors", st.Errors, st.FailedCount)
+	}
+
+	// Pause: the scan waits for the cooldown after every trip, then finishes
+	cfg.BreakerAction = BreakerPause
+	cfg.BreakerCooldown = 1
+	cfg.BreakerWindow = 10
+	s := New(cfg)
+	go func() {
+		for range s.Results() {
+		}
+	}()
+	paused := make(chan bool, 1)
+	go func() {
+		for st := range s.Progress() {
+			if st.Breaker != "" && s.Paused() {
+				select {
+				case paused <- true:
+				default:
+				}
+			}
+		}
+	}()
+	start := time.Now()
+	if err := s.Run(context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("scan took %v, want at least the cooldown", elapsed)
+	}
+	select {
+	case <-paused:
+	default:
+		t.Error("breaker never reported a pause")
+	}
+	if got := s.Stats().Errors.Status; got != len(words) {
+		t.Errorf("status errors = %d, want %d", got, len(words))
+	}
+}
+
+func TestScanner_ReplayProxy(t *testing.T) {
+	site := newTestSite(t)
+	var (
+		mu       sync.Mutex
+		replayed []string
+	)
+	// A plain HTTP proxy receives absolute-URI requests and forwards them
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		replayed = append(replayed, r.RequestURI)
+		mu.Unlock()
+		resp, err := http.DefaultTransport.RoundTrip(r.WithContext(context.Background()))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		defer resp.Body.Close()
+		w.WriteHeader(resp.StatusCode)
+		io.Copy(w, resp.Body)
+	}))
+	defer proxy.Close()
+
+	for _, transport := range []string{TransportFastHTTP, TransportNetHTTP} {
+		t.Run(transport, func(t *testing.T) {
+			replayed = nil
+			cfg := testConfig(site.URL, writeWordlist(t, "index.html", "admin", "missing"))
+			cfg.Transport = transport
+			cfg.ReplayProxy = proxy.URL
+			if got, want := runScan(t, cfg), []string{"/admin", "/index.html"}; !reflect.DeepEqual(got, want) {
+				t.Fatalf("results = %v, want %v", got, want)
+			}
+			sort.Strings(replayed)
+			if want := []string{site.URL + "/admin", site.URL + "/index.html"}; !reflect.DeepEqual(replayed, want) {
+				t.Errorf("replayed = %v, want only the results, %v", replayed, want)
+			}
+		})
+	}
+
+	// An https target is replayed through an intercepting proxy that answers
+	// CONNECT with its own certificate, which the scan does not trust
+	secure := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/admin" {
+			http.NotFound(w, r)
+		}
+	}))
+	defer secure.Close()
+	caFile := writePEM(t, t.TempDir(), "ca.pem", "CERTIFICATE", secure.Certificate().Raw)
+	burpCert := newServerCert(t)
+	burp := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n")
+		tlsConn := tls.Server(conn, &tls.Config{Certificates: []tls.Certificate{burpCert}})
+		req, err := http.ReadRequest(bufio.NewReader(tlsConn))
+		if err != nil {
+			return
+		}
+		mu.Lock()
+		replayed = append(replayed, "https://"+req.Host+req.URL.Path)
+		mu.Unlock()
+		io.WriteString(tlsConn, "HTTP/1.1 200 OK\r\nContent-Length: 0\r\nConnection: close\r\n\r\n")
+	}))
+	defer burp.Close()
+
+	for _, transport := range []string{TransportFastHTTP, TransportNetHTTP} {
+		t.Run(transport+" https", func(t *testing.T) {
+			replayed = nil
+			cfg := testConfig(secure.URL, writeWordlist(t, "admin", "missing"))
+			cfg.Transport = transport
+			cfg.CACert = caFile
+			cfg.ReplayProxy = burp.URL
+			s := New(cfg)
+			go func() {
+				for range s.Results() {
+				}
+			}()
+			if err := s.Run(context.Background()); err != nil {
+				t.Fatalf("Run: %v", err)
+			}
+			if want := []string{secure.URL + "/admin"}; !reflect.DeepEqual(replayed, want) {
+				t.Errorf("replayed = %v, want %v", replayed, want)
+			}
+			if stats := s.Stats(); stats.ReplayFailed != 0 {
+				t.Errorf("replay failed %d times: %s", stats.ReplayFailed, stats.ReplayError)
+			}
+		})
+	}
+
+	// A replay proxy that cannot be reached is counted, not fatal
+	cfg := testConfig(site.URL, writeWordlist(t, "index.html", "admin"))
+	cfg.ReplayProxy = "http://127.0.0.1:1"
+	s := New(cfg)
+	go func() {
+		for range s.Results() {
+		}
+	}()
+	if err := s.Run(context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if stats := s.Stats(); stats.ReplayFailed != 2 || stats.ReplayError == "" || stats.FailedCount != 0 {
+		t.Errorf("replay failed %d times (%q), scan failures %d; want 2, an error and 0", stats.ReplayFailed, stats.ReplayError, stats.FailedCount)
+	}
+
+	if err := ValidateTransport(&Config{ReplayProxy: "ftp://proxy"}); err == nil {
+		t.Error("expected an error for an invalid replay proxy")
+	}
+}
+
+func TestScanner_VHost(t *testing.T) {
+	// Unknown virtual hosts get the default site, with the same status
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Host {
+		case "admin.example.test":
+			io.WriteString(w, "admin panel\nlogin required\n")
+		case "dev.example.test":
+			io.WriteString(w, "staging build 42\n")
+		default:
+			io.WriteString(w, "default site for "+r.Host+"\n")
+		}
+	}))
+	defer srv.Close()
+
+	for _, transport := range []string{TransportFastHTTP, TransportNetHTTP} {
+		t.Run(transport, func(t *testing.T) {
+			cfg := testConfig(srv.URL, writeWordlist(t, "admin", "www", "dev", "mail"))
+			cfg.Transport = transport
+			cfg.VHost = true
+			cfg.VHostDomain = "example.test"
+			s := New(cfg)
+			var got []string
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				for r := range s.Results() {
+					if r.Path != srv.URL {
+						t.Errorf("path = %q, want the target URL", r.Path)
+					}
+					got = append(got, r.VHost)
+				}
+			}()
+			if err := s.Run(context.Background()); err != nil {
+				t.Fatalf("Run: %v", err)
+			}
+			<-done
+			sort.Strings(got)
+			if want := []string{"admin.example.test", "dev.example.test"}; !reflect.DeepEqual(got, want) {
+				t.Errorf("vhosts = %v, want %v", got, want)
+			}
+		})
+	}
+
+	// The target is an IP address: the domain must be given
+	cfg := testConfig(srv.URL, writeWordlist(t, "admin"))
+	cfg.VHost = true
+	if err := ValidateVHost(cfg); err == nil {
+		t.Error("expected an error without a vhost domain")
+	}
+	cfg.VHostDomain = "example.test"
+	cfg.Subdomain = true
+	if err := ValidateVHost(cfg); err == nil {
+		t.Error("expected an error with subdomain mode")
+	}
+}
+
+func TestResult_JSON(t *testing.T) {
+	r := Result{Path: "http://example.test/admin", Status: 200, Duration: 1234567 * time.Microsecond}
+	data, err := json.Marshal(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"duration_ms":1234`) || strings.Contains(string(data), `"duration"`) {
+		t.Errorf("JSON = %s, want duration_ms in milliseconds", data)
+	}
+	var back Result
+	if err := json.Unmarshal(data, &back); err != nil {
+		t.Fatal(err)
+	}
+	if back.Path != r.Path || back.Duration != 1234*time.Millisecond {
+		t.Errorf("decoded %+v", back)
+	}
+}
@@ -0,0 +1,256 @@
+package scanner
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// stateVersion is bumped whenever the State layout changes incompatibly
+const stateVersion = 2
+
+// defaultCheckpointInterval is used when Config.CheckpointInterval is not set
+const defaultCheckpointInterval = 30 * time.Second
+
+// State is a checkpoint of a scan: the configuration, where the producer of
+// every target is, the directories still waiting for recursion and the
+// results found so far. It is written periodically to Config.StateFile and
+// can be loaded back with LoadState and Restore to continue an interrupted scan.
+type State struct {
+	Version int       `json:"version"`
+	SavedAt time.Time `json:"saved_at"`
+	Config  Config    `json:"config"`
+
+	// One entry per target, in Config.Targets order
+	Hosts []HostState `json:"hosts"`
+
+	Results        []Result  `json:"results"`
+	Failures       []Failure `json:"failures,omitempty"`
+	ProcessedCount int       `json:"processed"`
+	ElapsedSeconds float64   `json:"elapsed_seconds"`
+}
+
+// HostState is the checkpointed position of a single target
+type HostState struct {
+	URL string `json:"url"`
+
+	// Producer position. Target is the directory being scanned when BaseDone
+	// is true; Cursor points to the next job to request in it.
+	BaseDone bool             `json:"base_done"`
+	Target   *RecursionTarget `json:"target,omitempty"`
+	Cursor   Cursor           `json:"cursor"`
+
+	// Pending recursion queue and every directory queued so far
+	Queue []RecursionTarget `json:"queue"`
+	Seen  []string          `json:"seen"`
+
+	Processed int `json:"processed"`
+}
+
+// Checkpoint captures the current state of the scan. Jobs that were handed to
+// the workers but have not finished are requested again after a restore, so
+// a checkpoint never skips a candidate.
+func (s *Scanner) Checkpoint() State {
+	st := State{
+		Version: stateVersion,
+		SavedAt: time.Now().UTC(),
+		Config:  *s.config,
+	}
+	stats := s.Stats()
+	for _, h := range s.hosts {
+		hs := h.checkpoint()
+		hs.Processed = stats.Hosts[h.index].Processed
+		st.Hosts = append(st.Hosts, hs)
+	}
+	st.Results = s.Found()
+	st.Failures = s.Failures()
+	st.ProcessedCount = stats.ProcessedCount
+	st.ElapsedSeconds = s.activeDuration().Seconds()
+	return st
+}
+
+// checkpoint captures the producer position and recursion queue of the host
+func (h *host) checkpoint() HostState {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	hs := HostState{
+		URL:      h.url,
+		BaseDone: h.baseDone,
+		Target:   h.target,
+		Cursor:   h.cursor,
+	}
+
+	// Restart from the oldest unfinished job. Its target may be older than the
+	// one being produced; later targets are then queued again from the start.
+	seqs := make([]uint64, 0, len(h.inflight))
+	for seq := range h.inflight {
+		seqs = append(seqs, seq)
+	}
+	sort.Slice(seqs, func(i, j int) bool { return seqs[i] < seqs[j] })
+
+	var requeue []RecursionTarget
+	if len(seqs) > 0 {
+		oldest := h.inflight[seqs[0]]
+		current := h.target
+		hs.BaseDone = oldest.target != nil
+		hs.Target = oldest.target
+		hs.Cursor = oldest.cursor
+
+		last := oldest.target
+		for _, seq := range seqs[1:] {
+			if t := h.inflight[seq].target; t != last && t != nil {
+				requeue = append(requeue, *t)
+				last = t
+			}
+		}
+		if current != nil && current != last {
+			requeue = append(requeue, *current)
+		}
+	}
+	hs.Queue = append(requeue, h.recursionQueue...)
+	for dir := range h.recursionSeen {
+		hs.Seen = append(hs.Seen, dir)
+	}
+	sort.Strings(hs.Seen)
+	return hs
+}
+
+// Restore loads a checkpoint into a scanner created with New(&st.Config).
+// It must be called before Run. Restored results are available from Found
+// and are not sent on Results again.
+func (s *Scanner) Restore(st *State) error {
+	if st.Version != stateVersion {
+		return fmt.Errorf("unsupported state version %d", st.Version)
+	}
+	if len(st.Hosts) != len(s.hosts) {
+		return fmt.Errorf("state has %d targets, scan has %d", len(st.Hosts), len(s.hosts))
+	}
+
+	for i, h := range s.hosts {
+		if st.Hosts[i].URL != h.url {
+			return fmt.Errorf("state target %s does not match %s", st.Hosts[i].URL, h.url)
+		}
+	}
+
+	seen := 0
+	for i, h := range s.hosts {
+		h.restore(st.Hosts[i])
+		seen += len(st.Hosts[i].Seen)
+	}
+
+	s.mu.Lock()
+	s.found = append([]Result{}, st.Results...)
+	for _, r := range st.Results {
+		s.known[r.Key()] = true
+	}
+	s.failures = append([]Failure{}, st.Failures...)
+	s.mu.Unlock()
+
+	s.priorElapsed = time.Duration(st.ElapsedSeconds * float64(time.Second))
+
+	s.statsMu.Lock()
+	s.stats.ProcessedCount = st.ProcessedCount
+	s.stats.FoundCount = len(st.Results)
+	s.stats.FailedCount = len(st.Failures)
+	for _, f := range st.Failures {
+		s.stats.Errors.add(f.Category)
+	}
+	for i, h := range s.hosts {
+		s.stats.Hosts[i].Processed = st.Hosts[i].Processed
+		for _, r := range st.Results {
+			if r.Target == h.url {
+				s.stats.Hosts[i].Found++
+			}
+		}
+	}
+	s.stats.RecursionCount = seen
+	s.stats.RecursionActive = seen > 0
+	s.stats.Elapsed = formatElapsed(s.priorElapsed)
+	s.statsMu.Unlock()
+	return nil
+}
+
+// restore loads the checkpointed position of the host
+func (h *host) restore(hs HostState) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.baseDone = hs.BaseDone
+	h.target = nil
+	if hs.BaseDone && hs.Target != nil {
+		target := *hs.Target
+		h.target = &target
+	}
+	h.cursor = hs.Cursor
+	h.recursionQueue = append([]RecursionTarget{}, hs.Queue...)
+	for _, dir := range hs.Seen {
+		h.recursionSeen[dir] = true
+	}
+}
+
+// SaveState writes a state file atomically (temporary file + rename)
+func SaveState(path string, st State) error {
+	data, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// LoadState reads a state file written by SaveState
+func LoadState(path string) (*State, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var st State
+	if err := json.Unmarshal(data, &st); err != nil {
+		return nil, fmt.Errorf("invalid state file %s: %w", path, err)
+	}
+	return &st, nil
+}
+
+// checkpointLoop writes the state file every checkpoint interval and once more
+// when done is closed. It is a no-op without Config.StateFile.
+func (s *Scanner) checkpointLoop(done chan struct{}) error {
+	if s.config.StateFile == "" {
+		return nil
+	}
+
+	interval := time.Duration(s.config.CheckpointInterval) * time.Second
+	if interval <= 0 {
+		interval = defaultCheckpointInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			// Periodic failures are retried on the next tick; the final write reports them.
+			SaveState(s.config.StateFile, s.Checkpoint())
+		case <-done:
+			if err := SaveState(s.config.StateFile, s.Checkpoint()); err != nil {
+				return fmt.Errorf("failed to write state file: %w", err)
+			}
+			return nil
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	// throttleMinRate is the lowest rate the throttle slows down to (req/s)
+	throttleMinRate = 1.0
+	// throttleCooldown is the minimum time between two slowdowns, so that a
+	// burst of 429s from concurrent workers halves the rate only once
+	throttleCooldown = time.Second
+	// throttleRampEvery is how long the rate must stay clean before each
+	// ramp-up step, and throttleRampStep the fraction of the ceiling added
+	throttleRampEvery = 5 * time.Second
+	throttleRampStep  = 0.1
+	// maxRetryAfter caps the pause requested by a Retry-After header
+	maxRetryAfter = 5 * time.Minute
+)
+
+// throttle is the adaptive rate controller of a host. Throttling statuses
+// (429, 503) and timeouts halve the request rate, Retry-After holds every
+// request until the given time, and the rate ramps back up step by step while
+// the target stays healthy. A nil *throttle never waits.
+type throttle struct {
+	mu sync.Mutex
+
+	limit   float64 // configured rate, 0 for unlimited
+	rate    float64 // current rate, 0 for unlimited
+	ceiling float64 // rate to ramp back up to
+
+	next         time.Time // earliest time of the next request at the current rate
+	holdUntil    time.Time // set by Retry-After
+	lastSlowdown time.Time
+	lastRamp     time.Time
+
+	// Observed request rate, measured over one-second windows, used as the
+	// starting point when an unlimited scan has to slow down
+	windowStart time.Time
+	windowCount int
+	observed    float64
+}
+
+func newThrottle(limit int) *throttle {
+	return &throttle{limit: float64(limit), rate: float64(limit), ceiling: float64(limit)}
+}
+
+// wait blocks until the next request may be sent. It returns false if ctx is
+// cancelled.
+func (t *throttle) wait(ctx context.Context) bool {
+	if t == nil {
+		return ctx.Err() == nil
+	}
+
+	t.mu.Lock()
+	now := time.Now()
+	t.rampUp(now)
+	t.count(now)
+	at := now
+	if t.holdUntil.After(at) {
+		at = t.holdUntil
+	}
+	if t.rate > 0 {
+		if t.next.After(at) {
+			at = t.next
+		}
+		t.next = at.Add(time.Duration(float64(time.Second) / t.rate))
+	}
+	t.mu.Unlock()
+
+	d := time.Until(at)
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// observe adapts the rate to a response
+func (t *throttle) observe(resp *fasthttp.Response) {
+	if t == nil {
+		return
+	}
+	switch resp.StatusCode() {
+	case fasthttp.StatusTooManyRequests, fasthttp.StatusServiceUnavailable:
+		t.slowdown(parseRetryAfter(string(resp.Header.Peek("Retry-After")), time.Now()))
+	}
+}
+
+// failed adapts the rate to a failed request: only timeouts slow it down
+func (t *throttle) failed(err error) {
+	if t != nil && isTimeout(err) {
+		t.slowdown(0)
+	}
+}
+
+// slowdown halves the rate, at most once per throttleCooldown, and holds
+// every request for retryAfter when it is set
+func (t *throttle) slowdown(retryAfter time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	if retryAfter > 0 {
+		if until := now.Add(min(retryAfter, maxRetryAfter)); until.After(t.holdUntil) {
+			t.holdUntil = until
+		}
+	}
+	if now.Sub(t.lastSlowdown) < throttleCooldown {
+		return
+	}
+
+	base := t.rate
+	if base == 0 {
+		// Unlimited: start from the rate actually reached and come back to
+		// it before lifting the limit again
+		base = max(t.observed, throttleMinRate*2)
+		t.ceiling = base
+	}
+	t.rate = max(base/2, throttleMinRate)
+	t.lastSlowdown, t.lastRamp = now, now
+}
+
+// rampUp raises the rate by one step when no slowdown happened for
+// throttleRampEvery. Once the ceiling is reached the configured limit (or
+// no limit) applies again. t.mu must be held.
+func (t *throttle) rampUp(now time.Time) {
+	if t.rate == 0 || t.rate >= t.ceiling || now.Sub(t.lastRamp) < throttleRampEvery {
+		return
+	}
+	t.rate += t.ceiling * throttleRampStep
+	t.lastRamp = now
+	if t.rate >= t.ceiling {
+		t.rate = t.limit
+	}
+}
+
+// count records a request in the observed rate window. t.mu must be held.
+func (t *throttle) count(now time.Time) {
+	if elapsed := now.Sub(t.windowStart); elapsed >= time.Second {
+		if !t.windowStart.IsZero() {
+			t.observed = float64(t.windowCount) / elapsed.Seconds()
+		}
+		t.windowStart, t.windowCount = now, 0
+	}
+	t.windowCount++
+}
+
+// current returns the effective rate (0 for unlimited) and whether the host
+// is currently slowed down
+func (t *throttle) current() (float64, bool) {
+	if t == nil {
+		return 0, false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.rate, t.rate != t.limit || t.holdUntil.After(time.Now())
+}
+
+// parseRetryAfter reads a Retry-After value: delay seconds or an HTTP date
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(value); err == nil {
+		return time.Duration(max(secs, 0)) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(at.Sub(now), 0)
+	}
+	return 0
+}
+
+// isTimeout reports whether a request failed because of a timeout
+func isTimeout(err error) bool {
+	if errors.Is(err, fasthttp.ErrTimeout) || errors.Is(err, fasthttp.ErrDialTimeout) ||
+		errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var ne net.Error
+	return errors.As(err, &ne) && ne.Timeout()
+}
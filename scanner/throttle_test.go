@@ -0,0 +1,64 @@
+package scanner
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"120", 2 * time.Minute},
+		{" 3 ", 3 * time.Second},
+		{"-5", 0},
+		{"Mon, 01 Jan 2024 12:00:30 GMT", 30 * time.Second},
+		{"Mon, 01 Jan 2024 11:00:00 GMT", 0},
+		{"soon", 0},
+	}
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.value, now); got != tt.want {
+			t.Errorf("parseRetryAfter(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestThrottle(t *testing.T) {
+	th := newThrottle(100)
+	th.slowdown(0)
+	th.slowdown(0) // within the cooldown: ignored
+	if rate, throttled := th.current(); rate != 50 || !throttled {
+		t.Fatalf("after slowdown: rate %v throttled %v, want 50 true", rate, throttled)
+	}
+
+	// Every clean interval adds 10% of the ceiling until the limit is back
+	for want := 60.0; want <= 100; want += 10 {
+		th.lastRamp = th.lastRamp.Add(-throttleRampEvery)
+		th.rampUp(time.Now())
+		if rate, _ := th.current(); rate != want {
+			t.Fatalf("ramp: rate %v, want %v", rate, want)
+		}
+	}
+	if _, throttled := th.current(); throttled {
+		t.Error("still throttled at the configured limit")
+	}
+
+	// Unlimited scans slow down from the observed rate and lift the limit
+	// again once they are back to it
+	th = newThrottle(0)
+	th.observed = 40
+	th.slowdown(0)
+	if rate, _ := th.current(); rate != 20 {
+		t.Fatalf("unlimited slowdown: rate %v, want 20", rate)
+	}
+	for i := 0; i < 10; i++ {
+		th.lastRamp = th.lastRamp.Add(-throttleRampEvery)
+		th.rampUp(time.Now())
+	}
+	if rate, throttled := th.current(); rate != 0 || throttled {
+		t.Errorf("after ramp-up: rate %v throttled %v, want unlimited", rate, throttled)
+	}
+}
@@ -0,0 +1,87 @@
+package scanner
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// tlsVersions maps the values accepted by Config.TLSMinVersion and
+// Config.TLSMaxVersion to their crypto/tls constants
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// newTLSConfig builds the TLS settings shared by every transport: certificate
+// verification, client certificate, extra trusted CAs, SNI override and
+// protocol versions.
+func newTLSConfig(cfg *Config) (*tls.Config, error) {
+	tc := &tls.Config{
+		InsecureSkipVerify: cfg.NoTLS,
+		ClientSessionCache: tls.NewLRUClientSessionCache(100),
+		ServerName:         cfg.SNI,
+	}
+
+	if cfg.ClientCert != "" || cfg.ClientKey != "" {
+		if cfg.ClientCert == "" {
+			return nil, fmt.Errorf("client key %s given without a client certificate", cfg.ClientKey)
+		}
+		// The key may be stored in the same PEM file as the certificate
+		key := cfg.ClientKey
+		if key == "" {
+			key = cfg.ClientCert
+		}
+		cert, err := tls.LoadX509KeyPair(cfg.ClientCert, key)
+		if err != nil {
+			return nil, fmt.Errorf("cannot load client certificate: %w", err)
+		}
+		tc.Certificates = []tls.Certificate{cert}
+	}
+
+	if cfg.CACert != "" {
+		pem, err := os.ReadFile(cfg.CACert)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read CA bundle: %w", err)
+		}
+		// Trust the bundle on top of the system roots
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no PEM certificate found in CA bundle %s", cfg.CACert)
+		}
+		tc.RootCAs = pool
+	}
+
+	var err error
+	if tc.MinVersion, err = parseTLSVersion(cfg.TLSMinVersion); err != nil {
+		return nil, err
+	}
+	if tc.MaxVersion, err = parseTLSVersion(cfg.TLSMaxVersion); err != nil {
+		return nil, err
+	}
+	if tc.MinVersion != 0 && tc.MaxVersion != 0 && tc.MinVersion > tc.MaxVersion {
+		return nil, fmt.Errorf("minimum TLS version %s is above the maximum %s", cfg.TLSMinVersion, cfg.TLSMaxVersion)
+	}
+	return tc, nil
+}
+
+// parseTLSVersion reads a TLS version such as "1.2" (an optional "tls"
+// prefix is accepted). An empty value returns 0, the crypto/tls default.
+func parseTLSVersion(value string) (uint16, error) {
+	v := strings.TrimPrefix(strings.ToLower(strings.TrimSpace(value)), "tls")
+	if v == "" {
+		return 0, nil
+	}
+	version, ok := tlsVersions[strings.TrimPrefix(v, "v")]
+	if !ok {
+		return 0, fmt.Errorf("invalid TLS version %q (use 1.0, 1.1, 1.2 or 1.3)", value)
+	}
+	return version, nil
+}
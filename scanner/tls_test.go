@@ -0,0 +1,185 @@
+package scanner
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// writePEM writes one PEM block to a file in dir and returns its path
+func writePEM(t *testing.T, dir, name, kind string, der []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: kind, Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// newClientCert creates a CA and a client certificate signed by it, and
+// returns the CA pool and the PEM files of the certificate and its key
+func newClientCert(t *testing.T, dir string) (*x509.CertPool, string, string) {
+	t.Helper()
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test client CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "scanner-client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, ca, &key.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+	return pool, writePEM(t, dir, "client.pem", "CERTIFICATE", certDER), writePEM(t, dir, "client.key", "EC PRIVATE KEY", keyDER)
+}
+
+// newServerCert creates a self-signed certificate for 127.0.0.1, distinct
+// from the one shared by httptest servers
+func newServerCert(t *testing.T) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber: big.NewInt(3),
+		Subject:      pkix.Name{CommonName: "intercepting proxy"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, &x509.Certificate{SerialNumber: big.NewInt(3), Subject: pkix.Name{CommonName: "intercepting proxy"}}, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func TestTransport_MutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	clientCAs, cert, key := newClientCert(t, dir)
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.TLS.ServerName+"|"+r.TLS.PeerCertificates[0].Subject.CommonName)
+	}))
+	srv.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0)
+	srv.StartTLS()
+	defer srv.Close()
+	// The test server certificate is valid for example.com and 127.0.0.1
+	ca := writePEM(t, dir, "ca.pem", "CERTIFICATE", srv.Certificate().Raw)
+
+	get := func(cfg *Config) (string, error) {
+		tr, err := NewTransport(cfg)
+		if err != nil {
+			t.Fatalf("NewTransport: %v", err)
+		}
+		req := fasthttp.AcquireRequest()
+		resp := fasthttp.AcquireResponse()
+		defer fasthttp.ReleaseRequest(req)
+		defer fasthttp.ReleaseResponse(resp)
+		req.SetRequestURI(srv.URL + "/")
+		if err := tr.Do(req, resp, time.Now().Add(5*time.Second)); err != nil {
+			return "", err
+		}
+		return string(resp.Body()), nil
+	}
+
+	for _, transport := range []string{TransportFastHTTP, TransportNetHTTP} {
+		t.Run(transport, func(t *testing.T) {
+			cfg := &Config{Transport: transport, Threads: 1, Timeout: 5,
+				ClientCert: cert, ClientKey: key, CACert: ca, SNI: "example.com"}
+			body, err := get(cfg)
+			if err != nil {
+				t.Fatalf("Do: %v", err)
+			}
+			if want := "example.com|scanner-client"; body != want {
+				t.Errorf("body = %q, want %q", body, want)
+			}
+
+			noCert := *cfg
+			noCert.ClientCert, noCert.ClientKey = "", ""
+			if _, err := get(&noCert); err == nil {
+				t.Error("expected the server to reject a client without certificate")
+			}
+			noCA := *cfg
+			noCA.CACert = ""
+			if _, err := get(&noCA); err == nil {
+				t.Error("expected an unknown authority error without the CA bundle")
+			}
+		})
+	}
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	tc, err := newTLSConfig(&Config{TLSMinVersion: "1.2", TLSMaxVersion: "TLS1.3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tc.MinVersion != tls.VersionTLS12 || tc.MaxVersion != tls.VersionTLS13 {
+		t.Errorf("versions = %x-%x, want TLS 1.2-1.3", tc.MinVersion, tc.MaxVersion)
+	}
+
+	for _, cfg := range []*Config{
+		{TLSMinVersion: "1.4"},
+		{TLSMinVersion: "1.3", TLSMaxVersion: "1.2"},
+		{ClientKey: "client.key"},
+		{ClientCert: filepath.Join(t.TempDir(), "missing.pem")},
+		{CACert: filepath.Join(t.TempDir(), "missing.pem")},
+	} {
+		if _, err := newTLSConfig(cfg); err == nil {
+			t.Errorf("newTLSConfig(%+v): expected an error", *cfg)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package scanner
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// Transport backends selectable with Config.Transport
+const (
+	TransportFastHTTP = "fasthttp"
+	TransportNetHTTP  = "nethttp"
+)
+
+// Transport sends the requests of a scan. Requests and responses are
+// fasthttp values whatever the backend, since that is how the scanner builds
+// and inspects them. Implementations must be safe for concurrent use.
+type Transport interface {
+	// Do sends req and stores the response in resp. A non-zero deadline
+	// aborts the request when it is reached.
+	Do(req *fasthttp.Request, resp *fasthttp.Response, deadline time.Time) error
+}
+
+// NewTransport builds the backend selected by Config.Transport: fasthttp by
+// default, or net/http, which negotiates HTTP/2 with TLS targets.
+func NewTransport(cfg *Config) (Transport, error) {
+	switch strings.ToLower(cfg.Transport) {
+	case "", TransportFastHTTP:
+		client, err := NewFastHTTPClient(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return &fasthttpTransport{client: client}, nil
+	case TransportNetHTTP:
+		t, err := newNetHTTPTransport(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return t, nil
+	}
+	return nil, fmt.Errorf("unknown transport %q (use %s or %s)", cfg.Transport, TransportFastHTTP, TransportNetHTTP)
+}
+
+// ValidateTransport checks the transport settings of cfg, including the
+// replay proxy
+func ValidateTransport(cfg *Config) error {
+	if _, err := NewTransport(cfg); err != nil {
+		return err
+	}
+	if _, err := newReplayTransport(cfg); err != nil {
+		return fmt.Errorf("replay proxy: %w", err)
+	}
+	return nil
+}
+
+// SetTransport replaces the transport built from the configuration, for
+// example with a fake in tests. It must be called before Run.
+func (s *Scanner) SetTransport(t Transport) {
+	s.transport = t
+}
+
+// fasthttpTransport is the default backend: HTTP/1.1 only, but fast
+type fasthttpTransport struct {
+	client *fasthttp.Client
+}
+
+func (t *fasthttpTransport) Do(req *fasthttp.Request, resp *fasthttp.Response, deadline time.Time) error {
+	if deadline.IsZero() {
+		return t.client.Do(req, resp)
+	}
+	return t.client.DoDeadline(req, resp, deadline)
+}
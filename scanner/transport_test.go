@@ -0,0 +1,247 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// fakeTransport answers from a map of path to status without any network
+type fakeTransport struct {
+	mu       sync.Mutex
+	statuses map[string]int
+	requests []string
+}
+
+func (f *fakeTransport) Do(req *fasthttp.Request, resp *fasthttp.Response, deadline time.Time) error {
+	path := string(req.URI().Path())
+	f.mu.Lock()
+	f.requests = append(f.requests, string(req.Header.Method())+" "+path)
+	f.mu.Unlock()
+
+	resp.Reset()
+	status, ok := f.statuses[path]
+	if !ok {
+		status = http.StatusNotFound
+	}
+	resp.SetStatusCode(status)
+	resp.SetBodyString("fake " + path)
+	return nil
+}
+
+func TestScanner_FakeTransport(t *testing.T) {
+	fake := &fakeTransport{statuses: map[string]int{"/admin": 200, "/login": 301}}
+	cfg := testConfig("http://scanme.invalid", writeWordlist(t, "admin", "login", "nope"))
+	s := New(cfg)
+	s.SetTransport(fake)
+	var got []string
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for r := range s.Results() {
+			got = append(got, strings.TrimPrefix(r.Path, cfg.URL))
+		}
+	}()
+	if err := s.Run(context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	<-done
+	sort.Strings(got)
+	if want := []string{"/admin", "/login"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("results = %v, want %v", got, want)
+	}
+	if len(fake.requests) != 3 {
+		t.Errorf("requests = %v, want one per word", fake.requests)
+	}
+}
+
+// schemeTransport answers every request with 200, except https requests to
+// hosts that only serve http, which fail like a refused connection
+type schemeTransport struct {
+	mu       sync.Mutex
+	httpOnly map[string]bool
+	requests []string
+}
+
+func (f *schemeTransport) Do(req *fasthttp.Request, resp *fasthttp.Response, deadline time.Time) error {
+	scheme, host := string(req.URI().Scheme()), string(req.URI().Host())
+	f.mu.Lock()
+	f.requests = append(f.requests, scheme+"://"+host)
+	f.mu.Unlock()
+
+	if scheme == "https" && f.httpOnly[host] {
+		return errors.New("connection refused")
+	}
+	resp.Reset()
+	resp.SetStatusCode(http.StatusOK)
+	return nil
+}
+
+func TestScanner_TryBothSchemes(t *testing.T) {
+	fake := &schemeTransport{httpOnly: map[string]bool{"legacy.example.test": true}}
+	cfg := testConfig("http://example.test", writeWordlist(t, "admin", "legacy"))
+	cfg.Subdomain = true
+	cfg.TryBothSchemes = true
+	s := New(cfg)
+	s.SetTransport(fake)
+	var got []string
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for r := range s.Results() {
+			got = append(got, r.Path)
+		}
+	}()
+	if err := s.Run(context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	<-done
+	sort.Strings(got)
+	if want := []string{"http://legacy.example.test/", "https://admin.example.test/"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("results = %v, want %v", got, want)
+	}
+	sort.Strings(fake.requests)
+	want := []string{"http://legacy.example.test", "https://admin.example.test", "https://legacy.example.test"}
+	if !reflect.DeepEqual(fake.requests, want) {
+		t.Errorf("requests = %v, want %v", fake.requests, want)
+	}
+	if stats := s.Stats(); stats.FailedCount != 0 {
+		t.Errorf("failed = %d, want 0: the http fallback answered", stats.FailedCount)
+	}
+}
+
+// socksServer is a SOCKS5 stand-in without authentication that sends every
+// requested host on hosts and connects to it
+func socksServer(t *testing.T, hosts chan<- string) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				buf := make([]byte, 256)
+				// Greeting: version, methods; choose no authentication
+				io.ReadFull(conn, buf[:2])
+				io.ReadFull(conn, buf[:buf[1]])
+				conn.Write([]byte{5, 0})
+				// Request: version, connect, reserved, address type
+				io.ReadFull(conn, buf[:4])
+				var host string
+				switch buf[3] {
+				case 1:
+					io.ReadFull(conn, buf[:4])
+					host = net.IP(buf[:4]).String()
+				case 3:
+					io.ReadFull(conn, buf[:1])
+					name := make([]byte, buf[0])
+					io.ReadFull(conn, name)
+					host = string(name)
+				}
+				hosts <- host
+				io.ReadFull(conn, buf[:2])
+				port := int(buf[0])<<8 | int(buf[1])
+				target, err := net.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+				if err != nil {
+					conn.Write([]byte{5, 5, 0, 1, 0, 0, 0, 0, 0, 0})
+					return
+				}
+				defer target.Close()
+				conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+				go io.Copy(target, conn)
+				io.Copy(conn, target)
+			}(conn)
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func TestNetHTTPTransport_SOCKS5(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "via socks")
+	}))
+	defer srv.Close()
+	port := srv.URL[strings.LastIndex(srv.URL, ":")+1:]
+	hosts := make(chan string, 4)
+	addr := socksServer(t, hosts)
+
+	// socks5 resolves host names locally, socks5h leaves them to the proxy
+	for scheme, want := range map[string]string{"socks5": "127.0.0.1", "socks5h": "localhost"} {
+		tr, err := NewTransport(&Config{Transport: TransportNetHTTP, Proxy: scheme + "://" + addr, Threads: 1, Timeout: 5})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req := fasthttp.AcquireRequest()
+		resp := fasthttp.AcquireResponse()
+		req.SetRequestURI("http://localhost:" + port + "/")
+		if err := tr.Do(req, resp, time.Now().Add(5*time.Second)); err != nil || string(resp.Body()) != "via socks" {
+			t.Errorf("%s: body %q, error %v", scheme, resp.Body(), err)
+		}
+		fasthttp.ReleaseRequest(req)
+		fasthttp.ReleaseResponse(resp)
+		if host := <-hosts; host != want {
+			t.Errorf("%s: proxy was asked for %q, want %q", scheme, host, want)
+		}
+	}
+}
+
+func TestNetHTTPTransport(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("X-Proto", r.Proto)
+		w.WriteHeader(http.StatusCreated)
+		io.WriteString(w, strings.Join([]string{r.Method, r.Host, r.Header.Get("X-Test"), string(body)}, "|"))
+	}))
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	defer srv.Close()
+
+	tr, err := NewTransport(&Config{Transport: TransportNetHTTP, NoTLS: true, Threads: 1, Timeout: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := fasthttp.AcquireRequest()
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseRequest(req)
+	defer fasthttp.ReleaseResponse(resp)
+	req.SetRequestURI(srv.URL + "/x")
+	req.Header.SetMethod(http.MethodPost)
+	req.Header.Set("Host", "vhost.test")
+	req.Header.Set("X-Test", "yes")
+	req.SetBodyString("data")
+
+	if err := tr.Do(req, resp, time.Now().Add(5*time.Second)); err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	if proto := string(resp.Header.Peek("X-Proto")); proto != "HTTP/2.0" {
+		t.Errorf("protocol = %q, want HTTP/2.0", proto)
+	}
+	if resp.StatusCode() != http.StatusCreated {
+		t.Errorf("status = %d", resp.StatusCode())
+	}
+	if body, want := string(resp.Body()), "POST|vhost.test|yes|data"; body != want {
+		t.Errorf("body = %q, want %q", body, want)
+	}
+
+	if _, err := NewTransport(&Config{Transport: "curl"}); err == nil {
+		t.Error("expected an error for an unknown transport")
+	}
+}
@@ -0,0 +1,67 @@
+package scanner
+
+import (
+	"fmt"
+	"net"
+	neturl "net/url"
+	"strings"
+)
+
+// vhostDomain returns the domain the labels are prepended to in vhost mode:
+// Config.VHostDomain, or the host name of the target URL
+func vhostDomain(cfg *Config, url string) (string, error) {
+	if cfg.VHostDomain != "" {
+		return strings.Trim(cfg.VHostDomain, "."), nil
+	}
+	domain, err := targetDomain(url)
+	if err != nil {
+		return "", fmt.Errorf("%w: set the vhost domain", err)
+	}
+	return domain, nil
+}
+
+// targetDomain returns the host name of a target URL, which may also be
+// given as a bare domain. IP addresses are rejected.
+func targetDomain(url string) (string, error) {
+	if !strings.Contains(url, "://") {
+		url = "http://" + url
+	}
+	u, err := neturl.Parse(url)
+	if err != nil || u.Hostname() == "" {
+		return "", fmt.Errorf("invalid target %q", url)
+	}
+	if net.ParseIP(u.Hostname()) != nil {
+		return "", fmt.Errorf("target %s is an IP address", u.Hostname())
+	}
+	return strings.TrimSuffix(u.Hostname(), "."), nil
+}
+
+// ValidateVHost checks the vhost mode settings of cfg
+func ValidateVHost(cfg *Config) error {
+	if !cfg.VHost {
+		return nil
+	}
+	switch {
+	case cfg.Subdomain:
+		return fmt.Errorf("vhost mode cannot be combined with subdomain mode")
+	case len(cfg.Wordlists) > 0:
+		return fmt.Errorf("vhost mode cannot be combined with keyword wordlists")
+	case cfg.Recursion:
+		return fmt.Errorf("vhost mode cannot be combined with recursion")
+	}
+	for _, url := range targetURLs(cfg) {
+		if _, err := vhostDomain(cfg, url); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// vhost returns the Host header of a job in vhost mode, or "" when the
+// request goes to the target's own host
+func (h *host) vhost(job Job) string {
+	if !h.s.config.VHost || job.Label == "" {
+		return ""
+	}
+	return job.Label + "." + h.vhostDomain
+}
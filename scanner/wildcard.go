@@ -0,0 +1,164 @@
+package scanner
+
+import (
+	"net"
+	neturl "net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// wildcardProbes is the number of random names resolved per zone. Several
+// probes catch wildcards that answer with a different address every time.
+const wildcardProbes = 3
+
+// wildcardZone is what the wildcard of one DNS zone (the parent of the
+// candidates, so nested zones get their own) answers: the addresses of its
+// random probes merged into one set, and with Config.WildcardContent the
+// profile of its HTTP response, learned per URL shape on first use.
+type wildcardZone struct {
+	once   sync.Once
+	probes []string        // random names resolved for the zone
+	values map[string]bool // nil when the zone has no wildcard
+
+	contentMu sync.Mutex
+	content   map[string]*baseline // by scheme and path
+}
+
+// wildcardFor returns the wildcard of zone, probing it on first use
+func (s *Scanner) wildcardFor(zone string) *wildcardZone {
+	s.wildcardMu.Lock()
+	z, ok := s.wildcards[zone]
+	if !ok {
+		z = &wildcardZone{content: make(map[string]*baseline)}
+		s.wildcards[zone] = z
+	}
+	s.wildcardMu.Unlock()
+
+	z.once.Do(func() {
+		for i := 0; i < wildcardProbes; i++ {
+			name := randomWord(8+6*i) + "." + zone
+			z.probes = append(z.probes, name)
+			values, err := s.lookup(name)
+			if err != nil {
+				continue
+			}
+			for _, v := range values {
+				if z.values == nil {
+					z.values = make(map[string]bool)
+				}
+				z.values[v] = true
+			}
+		}
+	})
+	return z
+}
+
+// matches reports whether any of the addresses (or CNAME targets) of a
+// candidate is one the wildcard answered with
+func (z *wildcardZone) matches(values []string) bool {
+	for _, v := range values {
+		if z.values[v] {
+			return true
+		}
+	}
+	return false
+}
+
+// lookup resolves name with the configured resolvers, or with the system
+// resolver when none is set
+func (s *Scanner) lookup(name string) ([]string, error) {
+	if s.resolver == nil {
+		return net.DefaultResolver.LookupHost(s.ctx, name)
+	}
+	records, _, err := s.resolver.resolve(s.ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return recordValues(records), nil
+}
+
+// recordValues returns the addresses and CNAME targets of records
+func recordValues(records []DNSRecord) []string {
+	values := make([]string, len(records))
+	for i, rec := range records {
+		values[i] = rec.Value
+	}
+	return values
+}
+
+// parentZone returns the zone a name belongs to: name without its first label
+func parentZone(name string) string {
+	_, zone, _ := strings.Cut(name, ".")
+	return zone
+}
+
+// wildcardCheck compares the candidate of a subdomain job with the wildcard
+// of its parent zone. It returns skip when the job should not be requested:
+// its addresses match the wildcard and no content check is configured. With
+// Config.WildcardContent it returns instead the wildcard's response profile,
+// and the response is dropped only when it matches as well. ok is false if
+// the scan was stopped.
+func (h *host) wildcardCheck(rawURL string) (skip bool, content *baseline, ok bool) {
+	u, err := neturl.Parse(rawURL)
+	if err != nil {
+		return false, nil, true
+	}
+	name := u.Hostname()
+	z := h.s.wildcardFor(parentZone(name))
+	if z.values == nil {
+		return false, nil, true
+	}
+	values, err := h.s.lookup(name)
+	if err != nil || !z.matches(values) {
+		return false, nil, true
+	}
+	if !h.s.config.WildcardContent {
+		return true, nil, true
+	}
+	content, ok = h.wildcardContent(z, u)
+	return false, content, ok
+}
+
+// wildcardContent returns the profile of the HTTP response of z's wildcard
+// for URLs shaped like u (same scheme, port and path), requesting the probe
+// names on first use. It is nil when the probes disagree. It returns false
+// if the scan was stopped.
+func (h *host) wildcardContent(z *wildcardZone, u *neturl.URL) (*baseline, bool) {
+	key := u.Scheme + " " + u.Port() + " " + u.RequestURI()
+	z.contentMu.Lock()
+	defer z.contentMu.Unlock()
+	if b, ok := z.content[key]; ok {
+		return b, true
+	}
+
+	req := fasthttp.AcquireRequest()
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseRequest(req)
+	defer fasthttp.ReleaseResponse(resp)
+
+	var probes []profile
+	for _, name := range z.probes {
+		if !h.s.gate.wait(h.s.ctx) || !h.pace() {
+			return nil, false
+		}
+		probe := *u
+		probe.Host = name
+		if port := u.Port(); port != "" {
+			probe.Host = net.JoinHostPort(name, port)
+		}
+		h.prepareRequest(req, Job{}, probe.String())
+		err := h.s.transport.Do(req, resp, time.Time{})
+		h.observe(resp, err)
+		if err != nil {
+			continue
+		}
+		probes = append(probes, measure(resp.StatusCode(), resp.Body(), 0))
+	}
+
+	b := learnBaseline(probes)
+	z.content[key] = b
+	return b, true
+}
@@ -0,0 +1,98 @@
+package scanner
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// wildcardZoneRecords has a round-robin wildcard on example.test, a nested
+// one on dev.example.test and a CDN host sharing an address with the wildcard
+var wildcardZoneRecords = map[string][]DNSRecord{
+	"*.example.test":       {{RecordA, "192.0.2.1"}, {RecordA, "192.0.2.2"}},
+	"admin.example.test":   {{RecordA, "192.0.2.50"}},
+	"cdn.example.test":     {{RecordA, "192.0.2.2"}},
+	"*.dev.example.test":   {{RecordA, "192.0.2.60"}},
+	"api.dev.example.test": {{RecordA, "192.0.2.61"}},
+}
+
+// hostTransport answers with a page per Host; unknown hosts get a parked
+// page that reflects the name, like a wildcard behind a CDN
+type hostTransport struct {
+	pages map[string]string
+}
+
+func (h *hostTransport) Do(req *fasthttp.Request, resp *fasthttp.Response, deadline time.Time) error {
+	host := string(req.URI().Host())
+	resp.Reset()
+	resp.SetStatusCode(http.StatusOK)
+	page, ok := h.pages[host]
+	if !ok {
+		page = "This domain " + host + " is parked\n"
+	}
+	resp.SetBodyString(page)
+	return nil
+}
+
+func TestScanner_WildcardDNS(t *testing.T) {
+	srv := newDNSServer(t, wildcardZoneRecords, dnsmessage.RCodeSuccess, false)
+	pages := &hostTransport{pages: map[string]string{
+		"admin.example.test":   "admin login\n",
+		"cdn.example.test":     "static assets served from the edge\n",
+		"api.dev.example.test": "{\"api\": true}\n",
+	}}
+
+	scan := func(cfg *Config) []string {
+		t.Helper()
+		cfg.Threads = 1
+		cfg.Resolvers = srv.addr
+		cfg.WildcardDetect = true
+		s := New(cfg)
+		s.SetTransport(pages)
+		var got []string
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for r := range s.Results() {
+				got = append(got, strings.TrimPrefix(strings.TrimSuffix(r.Path, "/"), "http://"))
+			}
+		}()
+		if err := s.Run(context.Background()); err != nil {
+			t.Fatalf("Run: %v", err)
+		}
+		<-done
+		sort.Strings(got)
+		return got
+	}
+	words := writeWordlist(t, "admin", "cdn", "www2", "api.dev", "foo.dev", "ftp")
+
+	// Addresses only: the CDN host shares an address with the wildcard
+	cfg := testConfig("http://example.test", words)
+	cfg.Subdomain = true
+	if got, want := scan(cfg), []string{"admin.example.test", "api.dev.example.test"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("addresses only: results = %v, want %v", got, want)
+	}
+
+	// With the content fingerprint the CDN host is kept: only its address
+	// matches the wildcard
+	cfg = testConfig("http://example.test", words)
+	cfg.Subdomain = true
+	cfg.WildcardContent = true
+	if got, want := scan(cfg), []string{"admin.example.test", "api.dev.example.test", "cdn.example.test"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("with content: results = %v, want %v", got, want)
+	}
+
+	// DNS-only mode
+	cfg = testConfig("example.test", words)
+	cfg.DNSOnly = true
+	if got, want := scan(cfg), []string{"admin.example.test", "api.dev.example.test"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("DNS mode: results = %v, want %v", got, want)
+	}
+}
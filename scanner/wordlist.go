@@ -0,0 +1,152 @@
+package scanner
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// StdinWordlist is the wordlist path that reads the entries from standard input
+const StdinWordlist = "-"
+
+// maxWordLength is the longest wordlist line accepted
+const maxWordLength = 1024 * 1024
+
+// Standard input can only be consumed once per process
+var (
+	stdinMu   sync.Mutex
+	stdin     io.Reader = os.Stdin
+	stdinUsed bool
+)
+
+// wordReader streams the entries of a wordlist: a file, a gzip-compressed
+// file or standard input. Blank lines and lines starting with # are skipped.
+type wordReader struct {
+	path   string
+	sc     *bufio.Scanner
+	closer io.Closer
+	word   string
+}
+
+// openWordlist opens a wordlist for reading. Gzip input is detected from its
+// magic bytes, so compressed files and compressed stdin both work.
+func openWordlist(path string) (*wordReader, error) {
+	var src io.Reader
+	var closer io.Closer
+	if path == StdinWordlist {
+		stdinMu.Lock()
+		used := stdinUsed
+		stdinUsed = true
+		stdinMu.Unlock()
+		if used {
+			return nil, fmt.Errorf("the wordlist from stdin was already read")
+		}
+		src = stdin
+	} else {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		src, closer = file, file
+	}
+
+	br := bufio.NewReader(src)
+	var r io.Reader = br
+	if magic, _ := br.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			if closer != nil {
+				closer.Close()
+			}
+			return nil, fmt.Errorf("wordlist %s: %w", path, err)
+		}
+		r = gz
+	}
+
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), maxWordLength)
+	return &wordReader{path: path, sc: sc, closer: closer}, nil
+}
+
+// next advances to the next entry, available in r.word. It returns false at
+// the end of the list or on a read error; see err.
+func (r *wordReader) next() bool {
+	for r.sc.Scan() {
+		line := strings.TrimRight(r.sc.Text(), "\r")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		r.word = line
+		return true
+	}
+	return false
+}
+
+// skip advances past the first n entries
+func (r *wordReader) skip(n int) {
+	for i := 0; i < n && r.next(); i++ {
+	}
+}
+
+// err returns the read error that stopped next, if any
+func (r *wordReader) err() error {
+	if err := r.sc.Err(); err != nil {
+		return fmt.Errorf("reading wordlist %s: %w", r.path, err)
+	}
+	return nil
+}
+
+func (r *wordReader) close() {
+	if r.closer != nil {
+		r.closer.Close()
+	}
+}
+
+// wordlistPaths returns the wordlists used by the scan
+func wordlistPaths(cfg *Config) []string {
+	if len(cfg.Wordlists) == 0 {
+		return []string{cfg.Wordlist}
+	}
+	paths := make([]string, len(cfg.Wordlists))
+	for i, wl := range cfg.Wordlists {
+		paths[i] = wl.Path
+	}
+	return paths
+}
+
+// checkWordlists opens every wordlist once so that a missing or unreadable
+// file fails the scan before it starts. Standard input is read in a single
+// pass, so it is only accepted where the list is walked exactly once.
+func (s *Scanner) checkWordlists() error {
+	cfg := s.config
+	fromStdin := 0
+	for i, path := range wordlistPaths(cfg) {
+		if path != StdinWordlist {
+			r, err := openWordlist(path)
+			if err != nil {
+				return err
+			}
+			r.close()
+			continue
+		}
+
+		fromStdin++
+		if fromStdin > 1 {
+			return fmt.Errorf("only one wordlist can be read from stdin")
+		}
+		singlePass := len(s.hosts) == 1
+		if s.keywordMode() {
+			singlePass = singlePass && (cfg.Mode == ModePitchfork || i == 0)
+		} else {
+			singlePass = singlePass && !cfg.Recursion && !(cfg.Subdomain && cfg.SubdomainPaths)
+		}
+		if !singlePass {
+			return fmt.Errorf("the wordlist from stdin can only be read once: it cannot be used with several targets, recursion, subdomain paths or as an inner clusterbomb list")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package scanner
+
+import (
+	"compress/gzip"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// readWords returns every entry of a wordlist
+func readWords(t *testing.T, path string) []string {
+	t.Helper()
+	r, err := openWordlist(path)
+	if err != nil {
+		t.Fatalf("openWordlist: %v", err)
+	}
+	defer r.close()
+	var words []string
+	for r.next() {
+		words = append(words, r.word)
+	}
+	if err := r.err(); err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	return words
+}
+
+// useStdin replaces standard input with data for the duration of the test
+func useStdin(t *testing.T, data string) {
+	t.Helper()
+	stdin, stdinUsed = strings.NewReader(data), false
+	t.Cleanup(func() { stdin, stdinUsed = os.Stdin, false })
+}
+
+func TestWordReader(t *testing.T) {
+	content := "# comment\nadmin\n\n  \r\nlogin\r\n#another\nbackup\n"
+	want := []string{"admin", "login", "backup"}
+
+	plain := filepath.Join(t.TempDir(), "words.txt")
+	if err := os.WriteFile(plain, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := readWords(t, plain); !reflect.DeepEqual(got, want) {
+		t.Errorf("plain = %q, want %q", got, want)
+	}
+
+	compressed := filepath.Join(t.TempDir(), "words.gz")
+	f, err := os.Create(compressed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gz := gzip.NewWriter(f)
+	gz.Write([]byte(content))
+	gz.Close()
+	f.Close()
+	if got := readWords(t, compressed); !reflect.DeepEqual(got, want) {
+		t.Errorf("gzip = %q, want %q", got, want)
+	}
+
+	useStdin(t, content)
+	if got := readWords(t, StdinWordlist); !reflect.DeepEqual(got, want) {
+		t.Errorf("stdin = %q, want %q", got, want)
+	}
+	if _, err := openWordlist(StdinWordlist); err == nil {
+		t.Error("stdin opened twice")
+	}
+}
+
+func TestScanner_WordlistReadError(t *testing.T) {
+	srv := newTestSite(t)
+	path := filepath.Join(t.TempDir(), "words.txt")
+	data := "index.html\n" + strings.Repeat("x", maxWordLength+1) + "\n"
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s := New(testConfig(srv.URL, path))
+	var found []string
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for r := range s.Results() {
+			found = append(found, r.Path)
+		}
+	}()
+	err := s.Run(context.Background())
+	<-done
+	if err == nil || !strings.Contains(err.Error(), "reading wordlist") {
+		t.Fatalf("Run = %v, want a read error", err)
+	}
+	if len(found) != 1 {
+		t.Errorf("found %v, want the entries read before the error", found)
+	}
+}
+
+func TestScanner_StdinWordlist(t *testing.T) {
+	srv := newTestSite(t)
+	useStdin(t, "index.html\nnope\n")
+	if got := runScan(t, testConfig(srv.URL, StdinWordlist)); !reflect.DeepEqual(got, []string{"/index.html"}) {
+		t.Errorf("results = %v", got)
+	}
+
+	// Recursion would walk stdin more than once
+	useStdin(t, "index.html\n")
+	cfg := testConfig(srv.URL, StdinWordlist)
+	cfg.Recursion = true
+	if err := New(cfg).Run(context.Background()); err == nil {
+		t.Error("expected an error for stdin with recursion")
+	}
+}
+
+func TestScanner_ClusterbombRestore(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	cfg := testConfig(srv.URL+"/A/B", "")
+	cfg.Wordlists = []KeywordWordlist{
+		{Path: writeWordlist(t, "a1", "a2"), Keyword: "A"},
+		{Path: writeWordlist(t, "b1", "b2", "b3"), Keyword: "B"},
+	}
+
+	// The cursor after a1/b3 points past the end of the inner list
+	s := New(cfg)
+	st := s.Checkpoint()
+	st.Hosts[0].Cursor = Cursor{Words: []int{0, 3}}
+	if err := s.Restore(&st); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for r := range s.Results() {
+			got = append(got, strings.TrimPrefix(r.Path, srv.URL))
+		}
+	}()
+	if err := s.Run(context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	<-done
+	sort.Strings(got)
+	if want := []string{"/a2/b1", "/a2/b2", "/a2/b3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("results = %v, want %v", got, want)
+	}
+}
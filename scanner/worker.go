@@ -0,0 +1,240 @@
+package scanner
+
+import (
+	"fmt"
+	neturl "net/url"
+	"strings"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+func (h *host) worker(f *filters) {
+	defer h.workers.Done()
+
+	req := fasthttp.AcquireRequest()
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseRequest(req)
+	defer fasthttp.ReleaseResponse(resp)
+
+	for job := range h.jobs {
+		// Hold here while the scan is paused. A stopped scan leaves the job
+		// unfinished so that a checkpoint requests it again.
+		if !h.s.gate.wait(h.s.ctx) {
+			return
+		}
+
+		h.s.statsMu.Lock()
+		h.s.stats.ProcessedCount++
+		elapsed := h.s.activeDuration()
+		if elapsed > 0 {
+			h.s.stats.RPS = float64(h.s.stats.ProcessedCount) / elapsed.Seconds()
+		}
+		h.s.stats.Elapsed = formatElapsed(elapsed)
+		h.s.stats.Hosts[h.index].Processed++
+		h.s.statsMu.Unlock()
+
+		// Rate limiting
+		if !h.pace() {
+			return
+		}
+
+		if h.s.config.Delay > 0 {
+			time.Sleep(time.Duration(h.s.config.Delay) * time.Millisecond)
+		}
+
+		if h.s.config.DNSOnly {
+			if !h.resolveJob(job) {
+				return
+			}
+			h.jobDone(job)
+			continue
+		}
+
+		url := h.buildURL(job)
+
+		// Wildcard DNS: skip the candidate, or keep the wildcard's response
+		// profile to compare with
+		var wildcard *baseline
+		if h.s.config.Subdomain && h.s.config.WildcardDetect && job.Label != "" {
+			skip, content, ok := h.wildcardCheck(url)
+			if !ok {
+				return
+			}
+			if skip {
+				h.jobDone(job)
+				continue
+			}
+			wildcard = content
+		}
+
+		h.prepareRequest(req, job, url)
+
+		h.s.statsMu.Lock()
+		h.s.stats.CurrentPath = url
+		h.s.statsMu.Unlock()
+
+		duration, failure, ok := h.send(req, resp)
+		if !ok {
+			return
+		}
+		// With TryBothSchemes a label that cannot be reached over https is
+		// tried again over http
+		if failure != nil && failure.Status == 0 && h.s.config.Subdomain && h.s.config.TryBothSchemes &&
+			job.Label != "" && strings.HasPrefix(url, "https://") {
+			url = "http://" + strings.TrimPrefix(url, "https://")
+			if wildcard != nil {
+				if _, wildcard, ok = h.wildcardCheck(url); !ok {
+					return
+				}
+			}
+			h.prepareRequest(req, job, url)
+			if duration, failure, ok = h.send(req, resp); !ok {
+				return
+			}
+		}
+		h.s.recordOutcome(failure != nil)
+		if failure != nil {
+			failure.Path = url
+			failure.Input = h.jobInputs(job)
+			failure.VHost = h.vhost(job)
+			h.s.recordFailure(h, *failure)
+		}
+
+		if failure == nil {
+			body := resp.Body()
+			p := measure(resp.StatusCode(), body, duration)
+
+			// Apply filters, drop soft-404 pages learned by calibration and
+			// wildcard responses, then check the matchers
+			if !f.filtered(p, body) && !h.softNotFound(job, p) && !wildcard.matches(p) {
+				statusCode := p.status
+				if f.matched(p, body) {
+					h.replay(req)
+					result := Result{
+						Path:     url,
+						Status:   statusCode,
+						Size:     p.size,
+						Lines:    p.lines,
+						Words:    p.words,
+						Duration: p.duration,
+					}
+					result.Input = h.jobInputs(job)
+					result.VHost = h.vhost(job)
+					location := string(resp.Header.Peek("Location"))
+					if isRedirect(statusCode) {
+						result.Location = resolveLocation(url, location)
+						if h.s.config.FollowRedirects {
+							h.followRedirects(req, &result)
+						}
+					}
+					h.s.emit(h, result)
+
+					// Queue directories for recursive scanning
+					if h.s.config.Recursion && !h.s.config.Subdomain && !h.s.config.VHost && job.Depth < h.s.config.MaxDepth {
+						if dir, ok := directoryURL(url, statusCode, location); ok {
+							h.queueRecursion(dir, job.Depth+1)
+						}
+					}
+				}
+			}
+		}
+
+		h.jobDone(job)
+	}
+}
+
+// buildURL turns a job into the URL to request
+func (h *host) buildURL(job Job) string {
+	var url string
+	// Subdomain fuzzing: handle job.Label and optional job.Path
+	if h.s.config.Subdomain && job.Label != "" {
+		// Extract scheme and host from the target URL
+		base := h.url
+		scheme := "http"
+		host := base
+		if strings.Contains(base, "://") {
+			parts := strings.SplitN(base, "://", 2)
+			scheme = parts[0]
+			host = parts[1]
+		}
+
+		// If host contains path, strip it
+		if strings.Contains(host, "/") {
+			host = strings.SplitN(host, "/", 2)[0]
+		}
+
+		// Build the candidate URL. With TryBothSchemes https is tried first
+		// and the worker falls back to http.
+		if h.s.config.TryBothSchemes {
+			scheme = "https"
+		}
+		if job.Path != "" {
+			// If path provided (cartesian), append it
+			p := strings.TrimLeft(job.Path, "/")
+			url = fmt.Sprintf("%s://%s.%s/%s", scheme, job.Label, host, p)
+		} else {
+			url = fmt.Sprintf("%s://%s.%s/", scheme, job.Label, host)
+		}
+	} else if h.s.config.VHost && job.Label != "" {
+		// Vhost mode: the label only goes into the Host header
+		url = h.url
+	} else if job.Values != nil {
+		// Keyword wordlists: every keyword is replaced in the template
+		url = h.s.substitute(h.url, job.Values)
+	} else if strings.Contains(job.URL, "://") {
+		url = job.URL
+	} else if h.templateMode {
+		// FUZZ somewhere in the request: the URL is used as is when it has no keyword
+		url = strings.Replace(h.url, DefaultKeyword, job.URL, 1)
+	} else {
+		// Normal path fuzzing
+		// If job.Path is set (shouldn't happen in normal mode), prefer it
+		if job.Path != "" {
+			url = fmt.Sprintf("%s/%s", strings.TrimRight(h.url, "/"), strings.TrimLeft(job.Path, "/"))
+		} else {
+			url = fmt.Sprintf("%s/%s", strings.TrimRight(h.url, "/"), job.URL)
+		}
+	}
+	return url
+}
+
+// formatElapsed renders a duration as HH:MM:SS
+func formatElapsed(d time.Duration) string {
+	return fmt.Sprintf("%02d:%02d:%02d", int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60)
+}
+
+// directoryURL reports whether a response looks like a directory and returns
+// its URL with a trailing slash. A redirect to the same path plus "/" or a
+// 200/401/403 on a path that already ends with "/" are treated as directories.
+func directoryURL(rawURL string, status int, location string) (string, bool) {
+	base, err := neturl.Parse(rawURL)
+	if err != nil {
+		return "", false
+	}
+	base.RawQuery = ""
+	base.Fragment = ""
+
+	switch status {
+	case 301, 302, 307, 308:
+		if location == "" || strings.HasSuffix(base.Path, "/") {
+			return "", false
+		}
+		loc, err := neturl.Parse(location)
+		if err != nil {
+			return "", false
+		}
+		target := base.ResolveReference(loc)
+		if target.Host != base.Host || target.Path != base.Path+"/" {
+			return "", false
+		}
+		base.Path += "/"
+		return base.String(), true
+	case 200, 401, 403:
+		if base.Path == "" || base.Path == "/" || !strings.HasSuffix(base.Path, "/") {
+			return "", false
+		}
+		return base.String(), true
+	}
+	return "", false
+}